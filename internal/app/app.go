@@ -2,22 +2,28 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-
 	"easy_proxies/internal/boxmgr"
 	"easy_proxies/internal/config"
 	"easy_proxies/internal/monitor"
 	"easy_proxies/internal/node"
+	"easy_proxies/internal/peering"
 	"easy_proxies/internal/proxypool"
 	"easy_proxies/internal/store"
 	"easy_proxies/internal/subscription"
+	"easy_proxies/internal/supervise"
+	"easy_proxies/internal/throttle"
+	"easy_proxies/internal/webhook"
 )
 
 // Run builds the runtime components from config and blocks until shutdown.
@@ -92,7 +98,15 @@ func Run(ctx context.Context, cfg *config.Config) error {
 	if err := boxMgr.Start(ctx); err != nil {
 		return fmt.Errorf("start box manager: %w", err)
 	}
-	defer boxMgr.Close()
+	st.SetNodeReloader(boxMgr)
+
+	// Subsystems below are managed by a supervisor tree rather than ad-hoc
+	// go/defer: a panic or repeated failure backs off and, past a failure
+	// threshold, suspends the subsystem for a cooldown instead of
+	// restart-looping it (or, for auto-speedtest, silently dying forever).
+	sv := supervise.New()
+	sv.Add(ctx, "boxmgr", &boxMgrService{mgr: boxMgr}, supervise.Options{})
+	defer sv.Stop()
 
 	// Initialize proxy pool with rotation mode from config
 	poolMode := store.PoolModeSequential
@@ -103,6 +117,8 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		poolMode = store.PoolModeLatencyFirst
 	case "weighted":
 		poolMode = store.PoolModeWeighted
+	case "p2c":
+		poolMode = store.PoolModeP2C
 	}
 
 	pool := proxypool.NewProxyPool(st, proxypool.Config{
@@ -110,12 +126,66 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		FallbackEnabled: true,
 		APIKey:          cfg.APIAuth.Key,
 	})
+	defer pool.Stop()
 
 	// Sync nodes from boxMgr to store with region detection
 	syncNodesToStore(boxMgr, st, cfg)
 
-	// Refresh pool nodes
-	pool.RefreshNodes()
+	retryTimeout := parseInterval(cfg.AutoSpeedtest.RetryTimeout, 10*time.Second)
+	retrySleep := parseInterval(cfg.AutoSpeedtest.RetrySleep, 2*time.Second)
+
+	// Block briefly for an initial speedtest pass so the pool refresh below
+	// runs against measured latencies rather than syncNodesToStore's -1
+	// "unknown" placeholders.
+	if cfg.AutoSpeedtest.Enabled {
+		warmup := &speedtestService{boxMgr: boxMgr, st: st, pool: pool, retryTimeout: retryTimeout, retrySleep: retrySleep}
+		warmupCtx, cancel := context.WithTimeout(ctx, retryTimeout)
+		warmup.runCycle(warmupCtx)
+		cancel()
+	}
+
+	// Load the initial node snapshot and start the pool's background store
+	// subscription, so it stays in sync without explicit refresh calls.
+	pool.Start(ctx)
+
+	// Set up bandwidth throttling: a global bucket plus per-node buckets
+	// keyed by outbound node name, so metered subscriptions can be capped.
+	limiter := throttle.NewLimiter(throttle.Config{
+		GlobalReadRate:  cfg.Throttle.GlobalReadRate,
+		GlobalWriteRate: cfg.Throttle.GlobalWriteRate,
+		Burst:           cfg.Throttle.Burst,
+	})
+	for name, rate := range cfg.Throttle.NodeRates {
+		limiter.SetNodeRate(name, throttle.Rates{ReadRate: rate.ReadRate, WriteRate: rate.WriteRate, Burst: rate.Burst})
+	}
+	boxMgr.SetListenerWrapper(limiter.WrapListener)
+
+	// Deliver lifecycle events (node up/down/blacklist, subscription
+	// add/delete) to any registered /api/webhooks endpoints.
+	dispatcher := webhook.New(st)
+	sv.Add(ctx, "webhooks", dispatcher, supervise.Options{})
+
+	// Replicate subscriptions and node health with any store.AddPeer'd
+	// instance. selfID only needs to be stable for as long as this process
+	// runs, to break last-writer-wins ties against a peer reporting at the
+	// exact same wall-clock time.
+	selfID, err := os.Hostname()
+	if err != nil || selfID == "" {
+		selfID = fmt.Sprintf("instance-%d", os.Getpid())
+	}
+	peerMgr := peering.New(st, selfID)
+	sv.Add(ctx, "peering", peerMgr, supervise.Options{})
+
+	// Start auto speedtest if enabled. Declared here (rather than next to
+	// sv.Add below) so the /api/health/speedtest closure registered in the
+	// monitor-server block further down can already capture it.
+	var speedtest *speedtestService
+	if cfg.AutoSpeedtest.Enabled {
+		interval := parseInterval(cfg.AutoSpeedtest.Interval, 30*time.Minute)
+		speedtest = &speedtestService{boxMgr: boxMgr, st: st, pool: pool, interval: interval, retryTimeout: retryTimeout, retrySleep: retrySleep}
+		sv.Add(ctx, "speedtest", speedtest, supervise.Options{})
+		fmt.Printf("✅ Auto speedtest enabled, interval: %v\n", interval)
+	}
 
 	// Wire up config to monitor server for settings API
 	if server := boxMgr.MonitorServer(); server != nil {
@@ -126,15 +196,37 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		poolHandler.SetConfig(cfg)
 		poolHandler.SetNodeManager(boxMgr)
 		poolHandler.SetMonitorManager(boxMgr.MonitorManager())
+		poolHandler.SetThrottleLimiter(limiter)
+		poolHandler.SetWebhookDispatcher(dispatcher)
 		poolHandler.RegisterRoutes(server.Mux(), server.WithAuth)
+
+		// Peers authenticate with their own shared bearer token (see
+		// store.Peer), not the server's API key, so this route is
+		// deliberately not wrapped in server.WithAuth.
+		server.Mux().HandleFunc(peering.StreamPath, peerMgr.ServeStream)
+
+		// Expose restart counts and last-error per subsystem
+		server.Mux().HandleFunc("/api/health/services", server.WithAuth(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"services": sv.Health()})
+		}))
+
+		// Expose per-attempt speedtest convergence progress
+		server.Mux().HandleFunc("/api/health/speedtest", server.WithAuth(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if speedtest == nil {
+				json.NewEncoder(w).Encode(map[string]any{"enabled": false})
+				return
+			}
+			json.NewEncoder(w).Encode(speedtest.progress.snapshot())
+		}))
 	}
 
 	// Create and start SubscriptionManager if enabled
 	var subMgr *subscription.Manager
 	if cfg.SubscriptionRefresh.Enabled && (len(cfg.Subscriptions) > 0 || len(cfg.SubscriptionConfigs) > 0) {
-		subMgr = subscription.New(cfg, boxMgr)
-		subMgr.Start()
-		defer subMgr.Stop()
+		subMgr = subscription.New(cfg, st, boxMgr)
+		sv.Add(ctx, "submgr", &subMgrService{mgr: subMgr}, supervise.Options{})
 
 		// Wire up subscription manager to monitor server for API endpoints
 		if server := boxMgr.MonitorServer(); server != nil {
@@ -142,12 +234,11 @@ func Run(ctx context.Context, cfg *config.Config) error {
 		}
 	}
 
-	// Start auto speedtest if enabled
-	if cfg.AutoSpeedtest.Enabled {
-		interval := parseInterval(cfg.AutoSpeedtest.Interval, 30*time.Minute)
-		go runAutoSpeedtest(ctx, boxMgr, st, pool, interval)
-		fmt.Printf("✅ Auto speedtest enabled, interval: %v\n", interval)
-	}
+	// Periodically resync node state from boxMgr/cfg.Nodes into the store,
+	// recovering from transient errors that syncNodesToStore's one-shot
+	// startup pass would otherwise leave stuck.
+	resyncInterval := parseInterval(cfg.ResyncFrequency, 30*time.Second)
+	sv.Add(ctx, "resync", &resyncService{boxMgr: boxMgr, st: st, cfg: cfg, interval: resyncInterval}, supervise.Options{})
 
 	// Wait for shutdown signal
 	sigCh := make(chan os.Signal, 1)
@@ -180,19 +271,21 @@ func detectNodeType(uri string) string {
 // syncNodesToStore syncs nodes from boxMgr to store with region detection
 func syncNodesToStore(boxMgr *boxmgr.Manager, st *store.Store, cfg *config.Config) {
 	for _, nodeCfg := range cfg.Nodes {
-		// Detect region from node name
-		regionInfo := node.DetectRegion(nodeCfg.Name)
+		// Detect region from node name, falling back to the node's actual
+		// server address for opaque names a GeoIP detector can place but a
+		// keyword match can't.
+		regionInfo := node.DetectRegionForHost(nodeCfg.Name, nodeCfg.Server)
 
 		enhancedNode := &store.EnhancedNode{
-			Name:       nodeCfg.Name,
-			URI:        nodeCfg.URI,
-			Port:       nodeCfg.Port,
-			Type:       detectNodeType(nodeCfg.URI),
-			Region:     regionInfo.Code,
-			RegionName: regionInfo.Name,
-			Status:     store.NodeStatusEnabled,
-			Available:  true,
-			Latency:    -1, // Unknown until tested
+			Name:         nodeCfg.Name,
+			URI:          nodeCfg.URI,
+			Port:         nodeCfg.Port,
+			Type:         detectNodeType(nodeCfg.URI),
+			Region:       regionInfo.Code,
+			RegionName:   regionInfo.Name,
+			Status:       store.NodeStatusEnabled,
+			Available:    true,
+			Latency:      -1, // Unknown until tested
 			LatencyLevel: store.LatencyLevelUnknown,
 		}
 
@@ -205,40 +298,248 @@ func syncNodesToStore(boxMgr *boxmgr.Manager, st *store.Store, cfg *config.Confi
 	}
 }
 
+// resyncService periodically re-walks cfg.Nodes and boxMgr's live snapshot
+// into the store: it adds newly-appeared nodes, removes stale ones, and
+// re-detects region for renamed nodes, so the store doesn't rely solely on
+// syncNodesToStore's one-shot startup pass.
+type resyncService struct {
+	boxMgr   *boxmgr.Manager
+	st       *store.Store
+	cfg      *config.Config
+	interval time.Duration
+}
 
-// runAutoSpeedtest runs periodic speed tests and updates latency groups
-func runAutoSpeedtest(ctx context.Context, boxMgr *boxmgr.Manager, st *store.Store, pool *proxypool.ProxyPool, interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (s *resyncService) Serve(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
 	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.resync()
+		}
+	}
+}
+
+func (s *resyncService) Stop() {}
+
+func (s *resyncService) resync() {
+	configured := make(map[string]bool, len(s.cfg.Nodes))
+
+	for _, nodeCfg := range s.cfg.Nodes {
+		configured[nodeCfg.Name] = true
+		regionInfo := node.DetectRegionForHost(nodeCfg.Name, nodeCfg.Server)
+
+		if existing, ok := s.st.GetNodeState(nodeCfg.Name); ok {
+			// Re-detect region in case the node was renamed.
+			existing.Region = regionInfo.Code
+			existing.RegionName = regionInfo.Name
+			s.st.UpdateNodeState(existing)
+			continue
+		}
+
+		// Newly-appeared node: add it with region detected, latency unknown.
+		enhancedNode := &store.EnhancedNode{
+			Name:         nodeCfg.Name,
+			URI:          nodeCfg.URI,
+			Port:         nodeCfg.Port,
+			Type:         detectNodeType(nodeCfg.URI),
+			Region:       regionInfo.Code,
+			RegionName:   regionInfo.Name,
+			Status:       store.NodeStatusEnabled,
+			Available:    true,
+			Latency:      -1,
+			LatencyLevel: store.LatencyLevelUnknown,
+		}
+		if nodeCfg.Source == config.NodeSourceSubscription {
+			enhancedNode.SubscriptionName = "subscription"
+		}
+		s.st.UpdateNodeState(enhancedNode)
+	}
+
+	liveNodes := make(map[string]bool)
+	if mgr := s.boxMgr.MonitorManager(); mgr != nil {
+		for _, snap := range mgr.Snapshot() {
+			liveNodes[snap.Name] = true
+
+			// Surface irrecoverable errors (e.g. a permanent auth failure
+			// reported by boxmgr) by forcing the node disabled with a
+			// reason, rather than letting stale state persist forever.
+			if snap.PermanentError != "" {
+				_ = s.st.DisableNode(snap.Name, snap.PermanentError)
+			}
+		}
+	}
+
+	// Remove nodes that are neither configured nor reported live by boxmgr.
+	for _, name := range s.st.ListNodeNames() {
+		if !configured[name] && !liveNodes[name] {
+			s.st.RemoveNodeState(name)
+		}
+	}
+}
+
+// speedtestProgress reports how far the current speedtest cycle has
+// converged, so operators can see whether nodes are still warming up.
+type speedtestProgress struct {
+	mu         sync.RWMutex
+	Attempt    int       `json:"attempt"`
+	TotalNodes int       `json:"total_nodes"`
+	Converged  int       `json:"converged"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (p *speedtestProgress) set(attempt, total, converged int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Attempt = attempt
+	p.TotalNodes = total
+	p.Converged = converged
+	p.UpdatedAt = time.Now()
+}
+
+func (p *speedtestProgress) snapshot() speedtestProgress {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return speedtestProgress{Attempt: p.Attempt, TotalNodes: p.TotalNodes, Converged: p.Converged, UpdatedAt: p.UpdatedAt}
+}
+
+// speedtestService runs periodic speed tests and updates latency groups.
+// It's supervised so a panic inside a tick no longer kills auto-speedtest
+// for the process lifetime: the supervisor recovers it and restarts.
+//
+// Each cycle retries probes that haven't converged yet (no available
+// latency), sleeping retrySleep between attempts, up to a retryTimeout
+// budget, then gives up until the next tick.
+type speedtestService struct {
+	boxMgr       *boxmgr.Manager
+	st           *store.Store
+	pool         *proxypool.ProxyPool
+	interval     time.Duration
+	retryTimeout time.Duration
+	retrySleep   time.Duration
+	progress     speedtestProgress
+}
+
+func (s *speedtestService) Serve(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.runCycle(ctx)
+			// runCycle's UpdateNodeState calls already propagate to the pool
+			// through its store subscription, so no explicit refresh here.
+			fmt.Println("✅ Auto speedtest completed")
+		}
+	}
+}
+
+func (s *speedtestService) Stop() {}
+
+// runCycle applies snapshots to the store, retrying nodes that haven't
+// converged (no measured latency yet) until retryTimeout elapses.
+func (s *speedtestService) runCycle(ctx context.Context) {
+	deadline := time.Now().Add(s.retryTimeout)
+	for attempt := 1; ; attempt++ {
+		total, converged := s.applySnapshots()
+		s.progress.set(attempt, total, converged)
+
+		if converged >= total || time.Now().After(deadline) {
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			return
+		case <-time.After(s.retrySleep):
+		}
+	}
+}
+
+// applySnapshots copies boxMgr's current snapshot into the store and
+// reports how many of the known nodes now have a measured latency.
+func (s *speedtestService) applySnapshots() (total, converged int) {
+	mgr := s.boxMgr.MonitorManager()
+	if mgr == nil {
+		return 0, 0
+	}
+
+	snapshots := mgr.Snapshot()
+	total = len(snapshots)
+	for _, snap := range snapshots {
+		snap := snap
+		// GuaranteedUpdateNode instead of a GetNodeState-then-UpdateNodeState
+		// pair, so a probe result can't clobber a status change (or another
+		// probe) that landed on this node in between.
+		err := s.st.GuaranteedUpdateNode(snap.Name, func(current *store.EnhancedNode) (*store.EnhancedNode, error) {
+			current.Latency = snap.LastLatencyMs
+			current.LatencyLevel = s.st.CalculateLatencyLevel(snap.LastLatencyMs)
+			current.Available = snap.Available
+			current.FailureCount = snap.FailureCount
+			current.SuccessCount = snap.SuccessCount
+			current.LastCheckAt = time.Now()
+			return current, nil
+		})
+		if err != nil && err != store.ErrNodeNotFound {
+			fmt.Printf("⚠️  failed to apply speedtest result for %s: %v\n", snap.Name, err)
+		}
+		if snap.LastLatencyMs > 0 {
+			converged++
+		}
+	}
+	return total, converged
+}
+
+// boxMgrService supervises the BoxManager: Serve blocks for the life of the
+// process (boxMgr.Start already launched its listeners) and returns an error
+// whenever the manager reports itself unhealthy, so the failure shows up in
+// the supervisor's restart count and last error and counts toward its
+// circuit breaker. Neither Serve nor the supervisor restarts the manager or
+// its listeners; the next Serve only resumes the health check.
+type boxMgrService struct {
+	mgr *boxmgr.Manager
+}
+
+func (s *boxMgrService) Serve(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
 		case <-ticker.C:
-			// Get all node snapshots from monitor
-			if mgr := boxMgr.MonitorManager(); mgr != nil {
-				snapshots := mgr.Snapshot()
-				for _, snap := range snapshots {
-					if nodeState, ok := st.GetNodeState(snap.Name); ok {
-						// Update latency
-						nodeState.Latency = snap.LastLatencyMs
-						nodeState.LatencyLevel = st.CalculateLatencyLevel(snap.LastLatencyMs)
-						nodeState.Available = snap.Available
-						nodeState.FailureCount = snap.FailureCount
-						nodeState.SuccessCount = snap.SuccessCount
-						nodeState.LastCheckAt = time.Now()
-						st.UpdateNodeState(nodeState)
-					}
-				}
+			if !s.mgr.Healthy() {
+				return fmt.Errorf("box manager reported unhealthy")
 			}
-			// Refresh pool after speedtest
-			pool.RefreshNodes()
-			fmt.Println("✅ Auto speedtest completed, pool refreshed")
 		}
 	}
 }
 
+func (s *boxMgrService) Stop() {
+	s.mgr.Close()
+}
+
+// subMgrService supervises the subscription manager's refresh loop.
+type subMgrService struct {
+	mgr *subscription.Manager
+}
+
+func (s *subMgrService) Serve(ctx context.Context) error {
+	s.mgr.Start()
+	<-ctx.Done()
+	return nil
+}
+
+func (s *subMgrService) Stop() {
+	s.mgr.Stop()
+}
+
 // parseInterval parses duration string, returns default if invalid
 func parseInterval(s string, defaultVal time.Duration) time.Duration {
 	if s == "" {
@@ -250,4 +551,3 @@ func parseInterval(s string, defaultVal time.Duration) time.Duration {
 	}
 	return d
 }
-
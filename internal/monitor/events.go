@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// NodeEventType names a state transition the monitor manager observes, as
+// opposed to store.EventType which covers persisted node/subscription
+// mutations. These are the higher-level, dashboard-facing transitions.
+type NodeEventType string
+
+const (
+	NodeEventUp                  NodeEventType = "node_up"
+	NodeEventDown                NodeEventType = "node_down"
+	NodeEventLatencyChanged      NodeEventType = "latency_changed"
+	NodeEventBlacklisted         NodeEventType = "blacklisted"
+	NodeEventSubscriptionAdded   NodeEventType = "subscription_added"
+	NodeEventSubscriptionDeleted NodeEventType = "subscription_deleted"
+	NodeEventReloadComplete      NodeEventType = "reload_complete"
+)
+
+// NodeEvent is one transition pushed to /api/events subscribers.
+type NodeEvent struct {
+	Type    NodeEventType `json:"type"`
+	Name    string        `json:"name,omitempty"`
+	Region  string        `json:"region,omitempty"`
+	Before  string        `json:"before,omitempty"` // latency level before the transition, for latency_changed
+	After   string        `json:"after,omitempty"`  // latency level after the transition, for latency_changed
+	Message string        `json:"message,omitempty"`
+	At      time.Time     `json:"at"`
+}
+
+// subscriberBufferSize bounds how many unread events a slow /api/events
+// client can fall behind by before the bus starts dropping its oldest ones,
+// so a stalled dashboard can never block the probe goroutines that publish.
+const subscriberBufferSize = 64
+
+// EventBus fans NodeEvents out to any number of /api/events subscribers.
+// Publish never blocks: a subscriber too slow to keep up has its oldest
+// buffered event dropped to make room for the new one.
+type EventBus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]chan NodeEvent
+}
+
+// NewEventBus creates an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]chan NodeEvent)}
+}
+
+// Subscribe registers a new subscriber and returns its event channel. The
+// caller must eventually call Unsubscribe with the same channel.
+func (b *EventBus) Subscribe() <-chan NodeEvent {
+	ch := make(chan NodeEvent, subscriberBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	b.subscribers[b.nextID] = ch
+	return ch
+}
+
+// Unsubscribe removes and closes a subscriber's channel.
+func (b *EventBus) Unsubscribe(ch <-chan NodeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, sub := range b.subscribers {
+		if sub == ch {
+			delete(b.subscribers, id)
+			close(sub)
+			return
+		}
+	}
+}
+
+// Publish stamps evt.At and fans it out to every subscriber, dropping the
+// subscriber's oldest buffered event instead of blocking if it's full.
+//
+// Manager's probe loop (not present in this source tree) is expected to call
+// Publish whenever it detects the corresponding transition.
+func (b *EventBus) Publish(evt NodeEvent) {
+	evt.At = time.Now()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- evt:
+			default:
+			}
+		}
+	}
+}
+
+// matches reports whether evt passes the region/event-type filters parsed
+// from an /api/events request's query params. An empty filter always
+// matches.
+func (evt NodeEvent) matches(regionFilter string, typeFilter map[NodeEventType]bool) bool {
+	if regionFilter != "" && evt.Region != regionFilter {
+		return false
+	}
+	if len(typeFilter) > 0 && !typeFilter[evt.Type] {
+		return false
+	}
+	return true
+}
@@ -7,11 +7,15 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"easy_proxies/internal/config"
 	"easy_proxies/internal/proxypool"
 	"easy_proxies/internal/store"
+	"easy_proxies/internal/throttle"
+	"easy_proxies/internal/webhook"
 )
 
 // ProxyPoolHandler handles proxy pool API requests
@@ -21,19 +25,95 @@ type ProxyPoolHandler struct {
 	cfg        *config.Config
 	nodeMgr    NodeManager
 	monitorMgr *Manager
+	limiter    *throttle.Limiter
+	metrics    proxyMetrics
+
+	defaultStrategy proxypool.SelectionStrategy
+	selectorsMu     sync.Mutex
+	selectors       map[proxypool.SelectionStrategy]*proxypool.Selector
+
+	events     *EventBus
+	dispatcher *webhook.Dispatcher
+}
+
+// selectorFor returns (creating if needed) the Selector for strategy,
+// falling back to h.defaultStrategy when strategy is empty.
+func (h *ProxyPoolHandler) selectorFor(strategy string) *proxypool.Selector {
+	s := proxypool.SelectionStrategy(strategy)
+	if s == "" {
+		s = h.defaultStrategy
+	}
+	if s == "" {
+		s = proxypool.StrategyLowestLatency
+	}
+
+	h.selectorsMu.Lock()
+	defer h.selectorsMu.Unlock()
+	if h.selectors == nil {
+		h.selectors = make(map[proxypool.SelectionStrategy]*proxypool.Selector)
+	}
+	if sel, ok := h.selectors[s]; ok {
+		return sel
+	}
+	sel := proxypool.NewSelector(s)
+	h.selectors[s] = sel
+	return sel
+}
+
+// proxyMetrics tracks selection counters for the /metrics endpoint. Scalar
+// counters use atomics so handleGetProxy/handleListProxies can bump them
+// without a lock; the by-label breakdowns share a mutex since Prometheus
+// label cardinality here is small and request-rate driven, not hot-path.
+type proxyMetrics struct {
+	getAttempts  atomic.Int64
+	getHits      atomic.Int64
+	getMisses    atomic.Int64
+	noAvailable  atomic.Int64
+	listRequests atomic.Int64
+
+	mu        sync.Mutex
+	byRegion  map[string]int64
+	byLatency map[string]int64
+}
+
+func (m *proxyMetrics) recordGet(region, latency string, hit bool) {
+	m.getAttempts.Add(1)
+	if hit {
+		m.getHits.Add(1)
+	} else {
+		m.getMisses.Add(1)
+		m.noAvailable.Add(1)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.byRegion == nil {
+		m.byRegion = make(map[string]int64)
+		m.byLatency = make(map[string]int64)
+	}
+	if region != "" {
+		m.byRegion[region]++
+	}
+	if latency != "" {
+		m.byLatency[latency]++
+	}
 }
 
 // NewProxyPoolHandler creates a new handler
 func NewProxyPoolHandler(pool *proxypool.ProxyPool, st *store.Store) *ProxyPoolHandler {
 	return &ProxyPoolHandler{
-		pool:  pool,
-		store: st,
+		pool:   pool,
+		store:  st,
+		events: NewEventBus(),
 	}
 }
 
 // SetConfig sets the configuration for subscription updates
 func (h *ProxyPoolHandler) SetConfig(cfg *config.Config) {
 	h.cfg = cfg
+	if cfg.Pool.Strategy != "" {
+		h.defaultStrategy = proxypool.SelectionStrategy(cfg.Pool.Strategy)
+	}
 }
 
 // SetNodeManager sets the node manager for triggering reloads
@@ -46,12 +126,73 @@ func (h *ProxyPoolHandler) SetMonitorManager(mgr *Manager) {
 	h.monitorMgr = mgr
 }
 
+// SetThrottleLimiter sets the bandwidth limiter backing /api/throttle
+func (h *ProxyPoolHandler) SetThrottleLimiter(limiter *throttle.Limiter) {
+	h.limiter = limiter
+}
+
+// SetWebhookDispatcher wires in the dispatcher that delivers lifecycle
+// events to registered /api/webhooks endpoints.
+func (h *ProxyPoolHandler) SetWebhookDispatcher(d *webhook.Dispatcher) {
+	h.dispatcher = d
+}
+
+// emitLifecycle publishes evtType on the monitor event stream (WebSocket +
+// SSE) and, if a webhook dispatcher is configured, queues it for delivery
+// to every registered webhook whose event mask matches. data is the
+// payload each webhook receives; name/region populate the NodeEvent seen
+// by WS/SSE subscribers.
+func (h *ProxyPoolHandler) emitLifecycle(evtType NodeEventType, name, region string, data any) {
+	h.events.Publish(NodeEvent{Type: evtType, Name: name, Region: region})
+	if h.dispatcher != nil {
+		h.dispatcher.Emit(string(evtType), data)
+	}
+}
+
+// NotifyAvailability should be called whenever the monitor manager's probe
+// loop observes a node's availability or latency bucket change. It isn't
+// wired to an in-tree caller yet: Manager's probe loop lives outside this
+// source snapshot.
+func (h *ProxyPoolHandler) NotifyAvailability(name, region string, available bool, before, after store.LatencyLevel) {
+	if available {
+		h.emitLifecycle(NodeEventUp, name, region, map[string]any{"name": name, "region": region})
+	} else {
+		h.emitLifecycle(NodeEventDown, name, region, map[string]any{"name": name, "region": region})
+	}
+	if before != after {
+		evt := NodeEvent{Type: NodeEventLatencyChanged, Name: name, Region: region, Before: string(before), After: string(after)}
+		h.events.Publish(evt)
+		if h.dispatcher != nil {
+			h.dispatcher.Emit(string(NodeEventLatencyChanged), map[string]any{"name": name, "region": region, "before": before, "after": after})
+		}
+	}
+}
+
+// checkIfMatch enforces the If-Match precondition the subscription and
+// config endpoints require before mutating: it rejects the request with
+// 412 Precondition Failed (returning ok=false, having already written the
+// response) unless the caller's If-Match header equals h.cfg's current
+// Fingerprint.
+func (h *ProxyPoolHandler) checkIfMatch(w http.ResponseWriter, r *http.Request) (fp string, ok bool) {
+	if h.cfg == nil {
+		return "", true
+	}
+	fp = h.cfg.Fingerprint()
+	if match := r.Header.Get("If-Match"); match != fp {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		writePoolJSON(w, map[string]any{"error": "If-Match does not match current config fingerprint", "fingerprint": fp})
+		return fp, false
+	}
+	return fp, true
+}
+
 // RegisterRoutes registers proxy pool API routes
 func (h *ProxyPoolHandler) RegisterRoutes(mux *http.ServeMux, withAuth func(http.HandlerFunc) http.HandlerFunc) {
 	// Proxy Pool API (public or with optional API key auth)
 	mux.HandleFunc("/api/proxy/get", h.handleGetProxy)
 	mux.HandleFunc("/api/proxy/list", h.handleListProxies)
 	mux.HandleFunc("/api/proxy/stats", h.handleStats)
+	mux.HandleFunc("/metrics", h.handleMetrics)
 
 	// Subscription Management API (requires auth)
 	mux.HandleFunc("/api/subscriptions", withAuth(h.handleSubscriptions))
@@ -64,6 +205,29 @@ func (h *ProxyPoolHandler) RegisterRoutes(mux *http.ServeMux, withAuth func(http
 	mux.HandleFunc("/api/groups/latency", withAuth(h.handleGroupsByLatency))
 	mux.HandleFunc("/api/groups/region", withAuth(h.handleGroupsByRegion))
 	mux.HandleFunc("/api/groups/subscription", withAuth(h.handleGroupsBySubscription))
+
+	// Throttle API (requires auth)
+	mux.HandleFunc("/api/throttle", withAuth(h.handleThrottle))
+
+	// Event stream API (requires auth). /api/events upgrades to WebSocket
+	// and pushes the richer monitor-level transitions (node_up/down,
+	// latency_changed, blacklisted, subscription_added/deleted,
+	// reload_complete); /api/events/sse is the SSE sibling of the same
+	// stream for clients that can't do WebSocket. /api/events/store keeps
+	// the original store.Event stream (node/subscription persistence
+	// changes) that used to live at /api/events before this split.
+	mux.HandleFunc("/api/events", withAuth(h.handleEventsWS))
+	mux.HandleFunc("/api/events/sse", withAuth(h.handleMonitorEventsSSE))
+	mux.HandleFunc("/api/events/store", withAuth(h.handleEvents))
+
+	// Transactional multi-op API (requires auth)
+	mux.HandleFunc("/api/txn", withAuth(h.handleTxn))
+
+	// Webhook registration API (requires auth)
+	mux.HandleFunc("/api/webhooks", withAuth(h.handleWebhooks))
+
+	// Whole-config GitOps API (requires auth)
+	mux.HandleFunc("/api/config", withAuth(h.handleConfig))
 }
 
 // --- Proxy Pool API ---
@@ -87,24 +251,27 @@ func (h *ProxyPoolHandler) handleGetProxy(w http.ResponseWriter, r *http.Request
 	// Parse filters
 	regionFilter := strings.ToUpper(r.URL.Query().Get("region"))
 	latencyFilter := r.URL.Query().Get("latency")
+	strategyParam := r.URL.Query().Get("strategy")
 
 	// Get available nodes from monitor manager (single source of truth)
 	var selectedNode *Snapshot
+	var selector *proxypool.Selector
 	if h.monitorMgr != nil {
 		snapshots := h.monitorMgr.Snapshot()
 		var candidates []Snapshot
-		
-		for _, snap := range snapshots {
+		byName := make(map[string]*Snapshot, len(snapshots))
+
+		for i, snap := range snapshots {
 			// Only include available nodes
 			if !snap.Available {
 				continue
 			}
-			
+
 			// Apply region filter
 			if regionFilter != "" && snap.Region != regionFilter {
 				continue
 			}
-			
+
 			// Apply latency filter
 			if latencyFilter != "" {
 				latencyLevel := h.classifyLatency(snap.LastLatencyMs)
@@ -112,30 +279,32 @@ func (h *ProxyPoolHandler) handleGetProxy(w http.ResponseWriter, r *http.Request
 					continue
 				}
 			}
-			
+
 			candidates = append(candidates, snap)
+			byName[snap.Name] = &snapshots[i]
 		}
-		
-		// Select best node (lowest latency with valid latency > 0)
+
 		if len(candidates) > 0 {
-			bestIdx := 0
-			bestLatency := int64(999999)
+			selector = h.selectorFor(strategyParam)
+
+			asNodes := make([]*store.EnhancedNode, len(candidates))
 			for i, c := range candidates {
-				lat := c.LastLatencyMs
-				if lat > 0 && lat < bestLatency {
-					bestLatency = lat
-					bestIdx = i
-				}
+				asNodes[i] = &store.EnhancedNode{Name: c.Name, Region: c.Region, Latency: c.LastLatencyMs}
+			}
+
+			if picked := selector.Select(asNodes); picked != nil {
+				selectedNode = byName[picked.Name]
 			}
-			selectedNode = &candidates[bestIdx]
 		}
 	}
 
 	if selectedNode == nil {
+		h.metrics.recordGet(regionFilter, latencyFilter, false)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		writePoolJSON(w, map[string]any{"error": "no available proxy"})
 		return
 	}
+	h.metrics.recordGet(regionFilter, latencyFilter, true)
 
 	// Return proxy URL
 	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", selectedNode.Port)
@@ -168,6 +337,7 @@ func (h *ProxyPoolHandler) handleListProxies(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	h.metrics.listRequests.Add(1)
 
 	// Check API key
 	apiKey := r.URL.Query().Get("key")
@@ -187,7 +357,7 @@ func (h *ProxyPoolHandler) handleListProxies(w http.ResponseWriter, r *http.Requ
 
 	// Build response from monitor manager (single source of truth for latency)
 	var proxies []map[string]any
-	
+
 	if h.monitorMgr != nil {
 		snapshots := h.monitorMgr.Snapshot()
 		for _, snap := range snapshots {
@@ -195,7 +365,7 @@ func (h *ProxyPoolHandler) handleListProxies(w http.ResponseWriter, r *http.Requ
 			if regionFilter != "" && snap.Region != regionFilter {
 				continue
 			}
-			
+
 			// Apply latency filter
 			if latencyFilter != "" {
 				latencyLevel := h.classifyLatency(snap.LastLatencyMs)
@@ -203,12 +373,12 @@ func (h *ProxyPoolHandler) handleListProxies(w http.ResponseWriter, r *http.Requ
 					continue
 				}
 			}
-			
+
 			// Only include available nodes
 			if !snap.Available {
 				continue
 			}
-			
+
 			proxies = append(proxies, map[string]any{
 				"proxy":         fmt.Sprintf("http://127.0.0.1:%d", snap.Port),
 				"name":          snap.Tag,
@@ -223,13 +393,13 @@ func (h *ProxyPoolHandler) handleListProxies(w http.ResponseWriter, r *http.Requ
 				"status":        "online",
 			})
 		}
-		
+
 		// Apply limit
 		if limit > 0 && len(proxies) > limit {
 			proxies = proxies[:limit]
 		}
 	}
-	
+
 	if proxies == nil {
 		proxies = []map[string]any{}
 	}
@@ -254,6 +424,95 @@ func (h *ProxyPoolHandler) classifyLatency(ms int64) store.LatencyLevel {
 	return store.LatencyLevelHigh
 }
 
+// subscriptionNameFor returns the store's SubscriptionName for a node,
+// or "" if it isn't tracked there (e.g. a node boxmgr reports that hasn't
+// been synced into the store yet), for labeling per-node metrics.
+func (h *ProxyPoolHandler) subscriptionNameFor(name string) string {
+	node, ok := h.store.GetNodeState(name)
+	if !ok {
+		return ""
+	}
+	return node.SubscriptionName
+}
+
+// handleMetrics exposes Prometheus text-format metrics derived from
+// monitorMgr.Snapshot() plus the selection counters tracked on h.metrics.
+// GET /metrics
+func (h *ProxyPoolHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	var snapshots []Snapshot
+	if h.monitorMgr != nil {
+		snapshots = h.monitorMgr.Snapshot()
+	}
+
+	available := 0
+	for _, snap := range snapshots {
+		if snap.Available {
+			available++
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP easy_proxies_nodes_total Total number of configured nodes\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_nodes_total gauge\n")
+	fmt.Fprintf(w, "easy_proxies_nodes_total %d\n", len(snapshots))
+
+	fmt.Fprintf(w, "# HELP easy_proxies_nodes_available Number of currently available nodes\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_nodes_available gauge\n")
+	fmt.Fprintf(w, "easy_proxies_nodes_available %d\n", available)
+
+	fmt.Fprintf(w, "# HELP easy_proxies_node_latency_ms Last measured latency per node\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_node_latency_ms gauge\n")
+	for _, snap := range snapshots {
+		fmt.Fprintf(w, "easy_proxies_node_latency_ms{name=%q,region=%q,subscription=%q} %d\n",
+			snap.Name, snap.Region, h.subscriptionNameFor(snap.Name), snap.LastLatencyMs)
+	}
+
+	fmt.Fprintf(w, "# HELP easy_proxies_node_failure_count Cumulative probe failures per node\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_node_failure_count gauge\n")
+	for _, snap := range snapshots {
+		fmt.Fprintf(w, "easy_proxies_node_failure_count{name=%q,region=%q,subscription=%q} %d\n",
+			snap.Name, snap.Region, h.subscriptionNameFor(snap.Name), snap.FailureCount)
+	}
+
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_get_attempts_total Total /api/proxy/get selection attempts\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_get_attempts_total counter\n")
+	fmt.Fprintf(w, "easy_proxies_proxy_get_attempts_total %d\n", h.metrics.getAttempts.Load())
+
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_get_hits_total Total /api/proxy/get calls that returned a proxy\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_get_hits_total counter\n")
+	fmt.Fprintf(w, "easy_proxies_proxy_get_hits_total %d\n", h.metrics.getHits.Load())
+
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_get_misses_total Total /api/proxy/get calls with no matching proxy\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_get_misses_total counter\n")
+	fmt.Fprintf(w, "easy_proxies_proxy_get_misses_total %d\n", h.metrics.getMisses.Load())
+
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_no_available_total Total \"no available proxy\" errors\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_no_available_total counter\n")
+	fmt.Fprintf(w, "easy_proxies_proxy_no_available_total %d\n", h.metrics.noAvailable.Load())
+
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_list_requests_total Total /api/proxy/list requests\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_list_requests_total counter\n")
+	fmt.Fprintf(w, "easy_proxies_proxy_list_requests_total %d\n", h.metrics.listRequests.Load())
+
+	h.metrics.mu.Lock()
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_get_region_total /api/proxy/get attempts by region filter\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_get_region_total counter\n")
+	for region, count := range h.metrics.byRegion {
+		fmt.Fprintf(w, "easy_proxies_proxy_get_region_total{region=%q} %d\n", region, count)
+	}
+	fmt.Fprintf(w, "# HELP easy_proxies_proxy_get_latency_filter_total /api/proxy/get attempts by latency filter\n")
+	fmt.Fprintf(w, "# TYPE easy_proxies_proxy_get_latency_filter_total counter\n")
+	for latency, count := range h.metrics.byLatency {
+		fmt.Fprintf(w, "easy_proxies_proxy_get_latency_filter_total{latency=%q} %d\n", latency, count)
+	}
+	h.metrics.mu.Unlock()
+}
 
 // handleStats returns pool statistics from monitor manager (single source of truth)
 func (h *ProxyPoolHandler) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -266,17 +525,19 @@ func (h *ProxyPoolHandler) handleStats(w http.ResponseWriter, r *http.Request) {
 	if h.monitorMgr != nil {
 		snapshots := h.monitorMgr.Snapshot()
 		stats := struct {
-			TotalNodes     int            `json:"total_nodes"`
-			AvailableNodes int            `json:"available_nodes"`
-			Mode           string         `json:"mode"`
-			ByLatency      map[string]int `json:"by_latency"`
-			ByRegion       map[string]int `json:"by_region"`
+			TotalNodes     int              `json:"total_nodes"`
+			AvailableNodes int              `json:"available_nodes"`
+			Mode           string           `json:"mode"`
+			ByLatency      map[string]int   `json:"by_latency"`
+			ByRegion       map[string]int   `json:"by_region"`
+			InFlight       map[string]int64 `json:"inflight_connections,omitempty"`
 		}{
 			TotalNodes:     len(snapshots),
 			AvailableNodes: 0,
 			Mode:           "monitor",
 			ByLatency:      make(map[string]int),
 			ByRegion:       make(map[string]int),
+			InFlight:       h.inflightSnapshot(),
 		}
 
 		for _, snap := range snapshots {
@@ -311,10 +572,17 @@ func (h *ProxyPoolHandler) handleStats(w http.ResponseWriter, r *http.Request) {
 func (h *ProxyPoolHandler) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
+		if h.cfg != nil {
+			w.Header().Set("ETag", h.cfg.Fingerprint())
+		}
 		subs := h.store.ListSubscriptions()
 		writePoolJSON(w, map[string]any{"subscriptions": subs})
 
 	case http.MethodPost:
+		if _, ok := h.checkIfMatch(w, r); !ok {
+			return
+		}
+
 		var sub store.Subscription
 		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
@@ -345,24 +613,30 @@ func (h *ProxyPoolHandler) handleSubscriptions(w http.ResponseWriter, r *http.Re
 			return
 		}
 
-		// Also add to config file for reload to pick up
+		// Also add to config file for reload to pick up, under the same
+		// lock the fingerprint above was read from.
 		configUpdated := false
+		newFP := ""
 		if h.cfg != nil {
-			// Check if URL already exists
-			exists := false
-			for _, existingURL := range h.cfg.Subscriptions {
-				if existingURL == sub.URL {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				h.cfg.Subscriptions = append(h.cfg.Subscriptions, sub.URL)
-				if err := h.cfg.SaveSubscriptions(); err == nil {
-					configUpdated = true
+			added := false
+			fp, err := h.cfg.DoLockedAction(r.Header.Get("If-Match"), func(c *config.Config) error {
+				for _, existingURL := range c.Subscriptions {
+					if existingURL == sub.URL {
+						return nil
+					}
 				}
+				c.Subscriptions = append(c.Subscriptions, sub.URL)
+				added = true
+				return nil
+			})
+			if err == nil && added {
+				configUpdated = true
+				newFP = fp
 			}
 		}
+		if newFP != "" {
+			w.Header().Set("ETag", newFP)
+		}
 
 		// Auto trigger reload after adding subscription (async with delay to avoid conflicts)
 		reloadTriggered := false
@@ -377,6 +651,8 @@ func (h *ProxyPoolHandler) handleSubscriptions(w http.ResponseWriter, r *http.Re
 			}()
 		}
 
+		h.emitLifecycle(NodeEventSubscriptionAdded, "", "", sub)
+
 		writePoolJSON(w, map[string]any{
 			"message":          "Subscription added",
 			"subscription":     sub,
@@ -417,6 +693,10 @@ func (h *ProxyPoolHandler) handleSubscriptionItem(w http.ResponseWriter, r *http
 		writePoolJSON(w, sub)
 
 	case http.MethodPut:
+		if _, ok := h.checkIfMatch(w, r); !ok {
+			return
+		}
+
 		var sub store.Subscription
 		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
@@ -432,6 +712,10 @@ func (h *ProxyPoolHandler) handleSubscriptionItem(w http.ResponseWriter, r *http
 		writePoolJSON(w, map[string]any{"message": "Subscription updated", "subscription": sub})
 
 	case http.MethodDelete:
+		if _, ok := h.checkIfMatch(w, r); !ok {
+			return
+		}
+
 		// Get subscription URL before deleting
 		sub, _ := h.store.GetSubscription(id)
 		subURL := ""
@@ -439,28 +723,45 @@ func (h *ProxyPoolHandler) handleSubscriptionItem(w http.ResponseWriter, r *http
 			subURL = sub.URL
 		}
 
+		if sub != nil && sub.Origin != "" && sub.Origin != store.SubscriptionOriginStatic {
+			w.WriteHeader(http.StatusConflict)
+			writePoolJSON(w, map[string]any{"error": "subscription was discovered via " + string(sub.Origin) + "; delete it at the source instead"})
+			return
+		}
+
 		if err := h.store.DeleteSubscription(id); err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			writePoolJSON(w, map[string]any{"error": err.Error()})
 			return
 		}
 
-		// Also remove from config file
+		// Also remove from config file, under the same lock the
+		// fingerprint above was read from.
 		configUpdated := false
+		newFP := ""
 		if h.cfg != nil && subURL != "" {
-			newSubs := make([]string, 0, len(h.cfg.Subscriptions))
-			for _, u := range h.cfg.Subscriptions {
-				if u != subURL {
-					newSubs = append(newSubs, u)
+			removed := false
+			fp, err := h.cfg.DoLockedAction(r.Header.Get("If-Match"), func(c *config.Config) error {
+				newSubs := make([]string, 0, len(c.Subscriptions))
+				for _, u := range c.Subscriptions {
+					if u != subURL {
+						newSubs = append(newSubs, u)
+					}
 				}
-			}
-			if len(newSubs) < len(h.cfg.Subscriptions) {
-				h.cfg.Subscriptions = newSubs
-				if err := h.cfg.SaveSubscriptions(); err == nil {
-					configUpdated = true
+				if len(newSubs) < len(c.Subscriptions) {
+					c.Subscriptions = newSubs
+					removed = true
 				}
+				return nil
+			})
+			if err == nil && removed {
+				configUpdated = true
+				newFP = fp
 			}
 		}
+		if newFP != "" {
+			w.Header().Set("ETag", newFP)
+		}
 
 		// Auto trigger reload after deleting subscription (async with delay to avoid conflicts)
 		reloadTriggered := false
@@ -475,6 +776,8 @@ func (h *ProxyPoolHandler) handleSubscriptionItem(w http.ResponseWriter, r *http
 			}()
 		}
 
+		h.emitLifecycle(NodeEventSubscriptionDeleted, "", "", map[string]any{"id": id, "url": subURL})
+
 		writePoolJSON(w, map[string]any{
 			"message":          "Subscription deleted",
 			"config_updated":   configUpdated,
@@ -550,8 +853,22 @@ func (h *ProxyPoolHandler) handleNodeStatus(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Refresh pool after status change
-	h.pool.RefreshNodes()
+	node, _ := h.store.GetNodeState(nodeName)
+	region := ""
+	if node != nil {
+		region = node.Region
+	}
+	switch status {
+	case store.NodeStatusEnabled:
+		h.emitLifecycle(NodeEventUp, nodeName, region, map[string]any{"name": nodeName, "region": region})
+	case store.NodeStatusDisabled:
+		h.emitLifecycle(NodeEventDown, nodeName, region, map[string]any{"name": nodeName, "region": region})
+	case store.NodeStatusBlacklisted:
+		h.emitLifecycle(NodeEventBlacklisted, nodeName, region, map[string]any{"name": nodeName, "region": region})
+	}
+
+	// h.store.SetNodeStatus already published the change, which the pool's
+	// store subscription picks up without an explicit refresh here.
 
 	writePoolJSON(w, map[string]any{
 		"message": fmt.Sprintf("Node %s status changed to %s", nodeName, status),
@@ -626,9 +943,9 @@ func (h *ProxyPoolHandler) handleGroupsByRegion(w http.ResponseWriter, r *http.R
 			}
 
 			node := map[string]any{
-				"name":          snap.Name,
-				"latency":       snap.LastLatencyMs,
-				"region_name":   snap.RegionName,
+				"name":        snap.Name,
+				"latency":     snap.LastLatencyMs,
+				"region_name": snap.RegionName,
 			}
 
 			if result[region] == nil {
@@ -674,6 +991,377 @@ func (h *ProxyPoolHandler) handleGroupsBySubscription(w http.ResponseWriter, r *
 	writePoolJSON(w, map[string]any{"groups": result})
 }
 
+// --- Throttle API ---
+
+// handleThrottle reads or updates global/per-node bandwidth limits.
+// GET  /api/throttle                         -> current global + per-node rates
+// POST /api/throttle {"node":"","read_rate":N,"write_rate":N,"burst":N}
+//
+//	node == "" updates the global buckets, otherwise the named node's buckets.
+func (h *ProxyPoolHandler) handleThrottle(w http.ResponseWriter, r *http.Request) {
+	if h.limiter == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writePoolJSON(w, map[string]any{"error": "throttling not enabled"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writePoolJSON(w, map[string]any{
+			"global": h.limiter.GlobalRate(),
+			"nodes":  h.limiter.NodeRates(),
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Node      string `json:"node"`
+			ReadRate  int64  `json:"read_rate"`
+			WriteRate int64  `json:"write_rate"`
+			Burst     int64  `json:"burst"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writePoolJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+
+		rates := throttle.Rates{ReadRate: req.ReadRate, WriteRate: req.WriteRate, Burst: req.Burst}
+		if req.Node == "" {
+			h.limiter.SetGlobalRate(rates)
+		} else {
+			h.limiter.SetNodeRate(req.Node, rates)
+		}
+
+		writePoolJSON(w, map[string]any{"message": "Throttle updated", "node": req.Node, "rates": rates})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Webhook Registration API ---
+
+// handleWebhooks lists and registers outbound webhook endpoints.
+// GET /api/webhooks / POST /api/webhooks {"url","secret","events"}
+func (h *ProxyPoolHandler) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writePoolJSON(w, map[string]any{"webhooks": h.store.ListWebhooks()})
+
+	case http.MethodPost:
+		var wh store.Webhook
+		if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writePoolJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+		if wh.URL == "" || wh.Secret == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writePoolJSON(w, map[string]any{"error": "url and secret are required"})
+			return
+		}
+		wh.Enabled = true
+		if err := h.store.AddWebhook(&wh); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			writePoolJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		writePoolJSON(w, map[string]any{"message": "Webhook registered", "webhook": wh})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Whole-config API ---
+
+// handleConfig serves GET/PUT of the full config document for GitOps-style
+// management, guarded by the same Fingerprint/If-Match scheme as the
+// subscription endpoints above.
+// GET /api/config -> 200 with ETag: <fingerprint>
+// PUT /api/config, If-Match: <fingerprint> -> replaces the whole config
+func (h *ProxyPoolHandler) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if h.cfg == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writePoolJSON(w, map[string]any{"error": "config not available"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", h.cfg.Fingerprint())
+		writePoolJSON(w, h.cfg)
+
+	case http.MethodPut:
+		var replacement config.Config
+		if err := json.NewDecoder(r.Body).Decode(&replacement); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writePoolJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+
+		fp, err := h.cfg.DoLockedAction(r.Header.Get("If-Match"), func(c *config.Config) error {
+			*c = replacement
+			return nil
+		})
+		if err == config.ErrFingerprintMismatch {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			writePoolJSON(w, map[string]any{"error": err.Error(), "fingerprint": h.cfg.Fingerprint()})
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			writePoolJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+
+		w.Header().Set("ETag", fp)
+		writePoolJSON(w, map[string]any{"message": "Config updated", "fingerprint": fp})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// --- Transactional API ---
+
+// txnOpRequest is a single op in a /api/txn batch. It layers "set-pool-mode"
+// (a ProxyPool concern) onto store.TxnOp's op vocabulary.
+type txnOpRequest struct {
+	store.TxnOp
+	Mode string `json:"mode,omitempty"` // for set-pool-mode
+}
+
+// handleTxn applies a JSON array of operations and returns per-op results.
+// POST /api/txn [{"op":"add-node","node":{...}}, {"op":"set-pool-mode","mode":"random"}, ...]
+//
+// Ops that touch store.Store (add-node, remove-node, enable-subscription,
+// get-or-empty-node) run atomically as a single batch via Store.ApplyTxn.
+// set-pool-mode is applied to the in-memory ProxyPool alongside them; it
+// can't share the store's lock, so it is not part of that atomic batch.
+func (h *ProxyPoolHandler) handleTxn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ops []txnOpRequest
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		writePoolJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+
+	storeOps := make([]store.TxnOp, 0, len(ops))
+	storeOpIndex := make([]int, 0, len(ops))
+	results := make([]store.TxnResult, len(ops))
+
+	for i, op := range ops {
+		if op.Op == "set-pool-mode" {
+			continue
+		}
+		storeOps = append(storeOps, op.TxnOp)
+		storeOpIndex = append(storeOpIndex, i)
+	}
+
+	storeResults := h.store.ApplyTxn(storeOps)
+	for i, res := range storeResults {
+		results[storeOpIndex[i]] = res
+	}
+
+	for i, op := range ops {
+		if op.Op != "set-pool-mode" {
+			continue
+		}
+		h.pool.SetMode(store.PoolMode(op.Mode))
+		results[i] = store.TxnResult{Op: op.Op, OK: true}
+	}
+
+	writePoolJSON(w, map[string]any{"results": results})
+}
+
+// --- Event Stream API ---
+
+// handleEvents streams store.Event as server-sent events: node status
+// transitions, latency-level changes, and subscription refreshes, so
+// clients no longer need to poll /api/nodes on a timer.
+// GET /api/events/store?name=xxx&region=US&type=node_updated
+func (h *ProxyPoolHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	nameFilter := r.URL.Query().Get("name")
+	regionFilter := strings.ToUpper(r.URL.Query().Get("region"))
+	typeFilter := store.EventType(r.URL.Query().Get("type"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := h.store.Subscribe()
+	defer h.store.Unsubscribe(events)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			if typeFilter != "" && evt.Type != typeFilter {
+				continue
+			}
+			if nameFilter != "" && (evt.Node == nil || evt.Node.Name != nameFilter) {
+				continue
+			}
+			if regionFilter != "" && (evt.Node == nil || evt.Node.Region != regionFilter) {
+				continue
+			}
+
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
+
+// parseEventFilters reads the region= and event= (comma-separated) query
+// params shared by the WebSocket and SSE monitor-event endpoints.
+func parseEventFilters(r *http.Request) (region string, types map[NodeEventType]bool) {
+	region = strings.ToUpper(r.URL.Query().Get("region"))
+
+	if raw := r.URL.Query().Get("event"); raw != "" {
+		types = make(map[NodeEventType]bool)
+		for _, t := range strings.Split(raw, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types[NodeEventType(t)] = true
+			}
+		}
+	}
+	return region, types
+}
+
+// handleEventsWS upgrades to a WebSocket and pushes monitor-level node
+// events (node_up, node_down, latency_changed, blacklisted,
+// subscription_added, subscription_deleted, reload_complete) as they
+// happen, replacing the "poll /api/proxy/list every few seconds" pattern.
+// GET /api/events?region=US&event=node_up,node_down
+func (h *ProxyPoolHandler) handleEventsWS(w http.ResponseWriter, r *http.Request) {
+	regionFilter, typeFilter := parseEventFilters(r)
+
+	ws, err := wsUpgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.Close()
+
+	events := h.events.Subscribe()
+	defer h.events.Unsubscribe(events)
+
+	done := make(chan struct{})
+	go ws.readLoop(done)
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			if !evt.matches(regionFilter, typeFilter) {
+				continue
+			}
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			if err := ws.WriteText(payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleMonitorEventsSSE is the Server-Sent-Events sibling of
+// handleEventsWS, for dashboards that can't do a WebSocket upgrade.
+// GET /api/events/sse?region=US&event=node_up,node_down
+func (h *ProxyPoolHandler) handleMonitorEventsSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	regionFilter, typeFilter := parseEventFilters(r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := h.events.Subscribe()
+	defer h.events.Unsubscribe(events)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			if !evt.matches(regionFilter, typeFilter) {
+				continue
+			}
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
+
 func writePoolJSON(w http.ResponseWriter, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
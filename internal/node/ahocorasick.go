@@ -0,0 +1,102 @@
+package node
+
+// ahoCorasick is a byte-oriented Aho-Corasick automaton: it finds every
+// pattern from a fixed dictionary that occurs in a text with a single pass
+// over the text, regardless of how many patterns there are. Matching at
+// the byte level (rather than rune level) works fine for UTF-8 substring
+// search since UTF-8 is self-synchronizing, so it also matches CJK
+// keywords and emoji flags without special-casing them.
+type ahoCorasick struct {
+	nodes []acNode
+}
+
+// acNode is one trie state. matches holds the indices (into the patterns
+// slice passed to newAhoCorasick) of every pattern ending at this state,
+// including those inherited from its failure link.
+type acNode struct {
+	children map[byte]int
+	fail     int
+	matches  []int
+}
+
+// newAhoCorasick builds an automaton over patterns. Patterns are matched
+// by their index in this slice, so callers look up metadata (region,
+// specificity tier, ...) by indexing back into the same slice they passed
+// in.
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{children: map[byte]int{}}}}
+	for i, p := range patterns {
+		ac.insert(p, i)
+	}
+	ac.buildFailureLinks()
+	return ac
+}
+
+func (ac *ahoCorasick) insert(pattern string, idx int) {
+	cur := 0
+	for i := 0; i < len(pattern); i++ {
+		b := pattern[i]
+		next, ok := ac.nodes[cur].children[b]
+		if !ok {
+			ac.nodes = append(ac.nodes, acNode{children: map[byte]int{}})
+			next = len(ac.nodes) - 1
+			ac.nodes[cur].children[b] = next
+		}
+		cur = next
+	}
+	ac.nodes[cur].matches = append(ac.nodes[cur].matches, idx)
+}
+
+// buildFailureLinks runs the standard BFS construction: a node's failure
+// link points at the longest proper suffix of its path that is itself a
+// path from the root, so a failed match can resume without rescanning any
+// of the text already consumed.
+func (ac *ahoCorasick) buildFailureLinks() {
+	var queue []int
+	for _, child := range ac.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		for b, u := range ac.nodes[r].children {
+			queue = append(queue, u)
+			fail := ac.transition(ac.nodes[r].fail, b)
+			ac.nodes[u].fail = fail
+			ac.nodes[u].matches = append(ac.nodes[u].matches, ac.nodes[fail].matches...)
+		}
+	}
+}
+
+// transition follows failure links from state until it finds one with a b
+// child, falling back to the root if none exists.
+func (ac *ahoCorasick) transition(state int, b byte) int {
+	for state != 0 {
+		if next, ok := ac.nodes[state].children[b]; ok {
+			return next
+		}
+		state = ac.nodes[state].fail
+	}
+	if next, ok := ac.nodes[0].children[b]; ok {
+		return next
+	}
+	return 0
+}
+
+// findAll runs the automaton over text and returns the index of every
+// pattern that occurs in it at least once.
+func (ac *ahoCorasick) findAll(text string) []int {
+	state := 0
+	var found []int
+	seen := make(map[int]bool)
+	for i := 0; i < len(text); i++ {
+		state = ac.transition(state, text[i])
+		for _, idx := range ac.nodes[state].matches {
+			if !seen[idx] {
+				seen[idx] = true
+				found = append(found, idx)
+			}
+		}
+	}
+	return found
+}
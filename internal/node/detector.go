@@ -0,0 +1,162 @@
+package node
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// RegionDetector resolves a RegionInfo from either a node's configured
+// name or its IP address. DetectRegion delegates to whichever detector
+// SetDefaultDetector last installed (the keyword/gazetteer matcher by
+// default), so existing callers don't need to change to benefit from a
+// more capable detector.
+type RegionDetector interface {
+	Detect(name string) RegionInfo
+	DetectByIP(ip net.IP) RegionInfo
+}
+
+// keywordDetector is the default RegionDetector: the gazetteer/
+// Aho-Corasick keyword matcher built in region.go's init. It has no
+// IP-based resolution of its own.
+type keywordDetector struct{}
+
+func (keywordDetector) Detect(name string) RegionInfo { return matchKeywords(name) }
+func (keywordDetector) DetectByIP(net.IP) RegionInfo  { return RegionInfo{} }
+
+var defaultDetector RegionDetector = keywordDetector{}
+
+// DefaultDetector returns the keyword/gazetteer-based RegionDetector that
+// backs DetectRegion before any SetDefaultDetector call, so callers can
+// wrap it (e.g. in a ChainDetector) without losing it.
+func DefaultDetector() RegionDetector {
+	return keywordDetector{}
+}
+
+// SetDefaultDetector overrides the RegionDetector that DetectRegion
+// delegates to. Typical use is opting into GeoIP-backed resolution for
+// nodes the keyword matcher can't place:
+//
+//	geoIP, err := node.NewGeoIPDetector("/path/to/GeoLite2-City.mmdb")
+//	node.SetDefaultDetector(node.NewChainDetector(node.DefaultDetector(), geoIP))
+func SetDefaultDetector(d RegionDetector) {
+	defaultDetector = d
+}
+
+// GeoIPDetector resolves RegionInfo by IP address against a MaxMind
+// GeoLite2-Country/City database. It has no name-based resolution of its
+// own; pair it with keywordDetector through ChainDetector to get both.
+type GeoIPDetector struct {
+	reader *geoip2.Reader
+}
+
+// NewGeoIPDetector opens the MaxMind .mmdb file at path. Callers own the
+// returned detector and should Close it on shutdown.
+func NewGeoIPDetector(path string) (*GeoIPDetector, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIPDetector{reader: reader}, nil
+}
+
+func (d *GeoIPDetector) Detect(name string) RegionInfo { return RegionInfo{} }
+
+// DetectByIP looks up ip's city record and maps it onto RegionInfo,
+// filling in Flag from the gazetteer when the resolved country is one we
+// know about.
+func (d *GeoIPDetector) DetectByIP(ip net.IP) RegionInfo {
+	if ip == nil {
+		return RegionInfo{}
+	}
+	record, err := d.reader.City(ip)
+	if err != nil {
+		return RegionInfo{}
+	}
+
+	info := RegionInfo{
+		Code:      record.Country.IsoCode,
+		Name:      record.Country.Names["en"],
+		Continent: record.Continent.Names["en"],
+		City:      record.City.Names["en"],
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}
+	if len(record.Subdivisions) > 0 {
+		info.Subdivision = info.Code + "-" + record.Subdivisions[0].IsoCode
+	}
+	if country, ok := countriesByCode[info.Code]; ok {
+		info.Flag = country.Flag
+	}
+	return info
+}
+
+// Close releases the underlying .mmdb file.
+func (d *GeoIPDetector) Close() error {
+	return d.reader.Close()
+}
+
+// ChainDetector tries a name-based detector first and, only when that
+// yields nothing, resolves name as a hostname and falls back to a GeoIP
+// lookup on its address. This is what recovers a real region for nodes
+// with opaque names like "vmess-042" that no keyword will ever match.
+type ChainDetector struct {
+	byName RegionDetector
+	byIP   RegionDetector
+}
+
+// NewChainDetector builds a ChainDetector from a name-based detector
+// (usually DefaultDetector()) and an IP-based one (usually a
+// *GeoIPDetector).
+func NewChainDetector(byName, byIP RegionDetector) *ChainDetector {
+	return &ChainDetector{byName: byName, byIP: byIP}
+}
+
+// Detect satisfies RegionDetector for callers with only a label to go on.
+// Its DNS fallback only helps when name happens to also be a resolvable
+// hostname; node display names like "vmess-042" never are. Callers that
+// also know the node's actual server address should call DetectHost
+// instead (see DetectRegionForHost), which resolves that address rather
+// than the label.
+func (c *ChainDetector) Detect(name string) RegionInfo {
+	if info := c.byName.Detect(name); info.Code != "" {
+		return info
+	}
+
+	ips, err := net.LookupIP(name)
+	if err != nil || len(ips) == 0 {
+		return RegionInfo{}
+	}
+	return c.byIP.DetectByIP(ips[0])
+}
+
+func (c *ChainDetector) DetectByIP(ip net.IP) RegionInfo {
+	return c.byIP.DetectByIP(ip)
+}
+
+// DetectHost tries the name-based detector first, same as Detect, but
+// falls back to resolving host — the node's actual server address —
+// instead of name, so opaque display names still get a GeoIP fallback
+// rather than silently failing to resolve as a DNS name.
+func (c *ChainDetector) DetectHost(name, host string) RegionInfo {
+	if info := c.byName.Detect(name); info.Code != "" {
+		return info
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return RegionInfo{}
+		}
+		ip = ips[0]
+	}
+	return c.byIP.DetectByIP(ip)
+}
+
+// HostAwareDetector is implemented by detectors — currently only
+// ChainDetector — that can resolve a node's actual server address instead
+// of just its display name. DetectRegionForHost uses it when available.
+type HostAwareDetector interface {
+	DetectHost(name, host string) RegionInfo
+}
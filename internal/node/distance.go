@@ -0,0 +1,50 @@
+package node
+
+import "math"
+
+// earthRadiusKm is the mean Earth radius used by the haversine formula
+// below; it's accurate enough for the region-clustering use cases this
+// package targets (no single country's centroid claims sub-kilometer
+// precision anyway).
+const earthRadiusKm = 6371.0
+
+// RegionDistance returns the great-circle distance in kilometers between
+// two RegionInfo centroids, via the haversine formula. Regions with no
+// known coordinates (the zero RegionInfo) are treated as (0, 0).
+func RegionDistance(a, b RegionInfo) float64 {
+	lat1, lon1 := toRadians(a.Latitude), toRadians(a.Longitude)
+	lat2, lon2 := toRadians(b.Latitude), toRadians(b.Longitude)
+
+	dLat := lat2 - lat1
+	dLon := lon2 - lon1
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// NearestRegion returns the known country whose centroid is closest to
+// (lat, lon), letting callers pick the closest exit relative to a user or
+// group nodes into geographic clusters without needing DetectRegion to
+// have matched anything.
+func NearestRegion(lat, lon float64) RegionInfo {
+	ref := RegionInfo{Latitude: lat, Longitude: lon}
+
+	var nearest RegionInfo
+	best := math.Inf(1)
+	for _, c := range countriesByCode {
+		candidate := RegionInfo{
+			Code: c.Code, Name: c.Name, Flag: c.Flag, Continent: c.Continent,
+			Latitude: c.Lat, Longitude: c.Lon,
+		}
+		if d := RegionDistance(ref, candidate); d < best {
+			best = d
+			nearest = candidate
+		}
+	}
+	return nearest
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -1,269 +1,270 @@
 package node
 
 import (
+	_ "embed"
+	"encoding/json"
 	"strings"
 )
 
-// RegionInfo contains region details
+// RegionInfo describes where a node is located, from coarsest (Continent)
+// to finest (City) detail. Subdivision and City are populated only when
+// DetectRegion matched a city or subdivision keyword (a city match also
+// fills in its parent subdivision); callers that only care about the
+// country can ignore them.
 type RegionInfo struct {
-	Code string // ISO 3166-1 alpha-2 code (e.g., "US", "JP")
-	Name string // Full name (e.g., "United States", "Japan")
-	Flag string // Emoji flag
+	Code        string  // ISO 3166-1 alpha-2 code (e.g., "US", "JP")
+	Name        string  // Full country name (e.g., "United States", "Japan")
+	Flag        string  // Emoji flag
+	Continent   string  // e.g. "Asia", "Europe"
+	Subdivision string  // ISO 3166-2 subdivision, when known
+	City        string  // City name, when a city-level keyword matched
+	Latitude    float64 // Centroid of the city if known, else of the country
+	Longitude   float64
 }
 
+//go:embed gazetteer.json
+var gazetteerJSON []byte
+
+// countryRecord and cityRecord mirror gazetteer.json's shape.
+type countryRecord struct {
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Continent string   `json:"continent"`
+	Lat       float64  `json:"lat"`
+	Lon       float64  `json:"lon"`
+	Flag      string   `json:"flag"`
+	Codes     []string `json:"codes"`
+	Names     []string `json:"names"`
+}
 
-// regionPatterns maps keywords to region info
-var regionPatterns = map[string]RegionInfo{
-	// East Asia
-	"hk":        {Code: "HK", Name: "Hong Kong", Flag: "🇭🇰"},
-	"hongkong":  {Code: "HK", Name: "Hong Kong", Flag: "🇭🇰"},
-	"hong kong": {Code: "HK", Name: "Hong Kong", Flag: "🇭🇰"},
-	"香港":        {Code: "HK", Name: "Hong Kong", Flag: "🇭🇰"},
-
-	"tw":      {Code: "TW", Name: "Taiwan", Flag: "🇹🇼"},
-	"taiwan":  {Code: "TW", Name: "Taiwan", Flag: "🇹🇼"},
-	"台湾":      {Code: "TW", Name: "Taiwan", Flag: "🇹🇼"},
-	"台灣":      {Code: "TW", Name: "Taiwan", Flag: "🇹🇼"},
-
-	"jp":     {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-	"japan":  {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-	"日本":     {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-	"东京":     {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-	"大阪":     {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-
-	"kr":     {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-	"korea":  {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-	"韩国":     {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-	"韓國":     {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-	"首尔":     {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-
-	"cn":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"china": {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"中国":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"中國":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"上海":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"北京":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"广州":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"深圳":    {Code: "CN", Name: "China", Flag: "🇨🇳"},
-
-	// Southeast Asia
-	"sg":        {Code: "SG", Name: "Singapore", Flag: "🇸🇬"},
-	"singapore": {Code: "SG", Name: "Singapore", Flag: "🇸🇬"},
-	"新加坡":       {Code: "SG", Name: "Singapore", Flag: "🇸🇬"},
-	"狮城":        {Code: "SG", Name: "Singapore", Flag: "🇸🇬"},
-
-	"my":       {Code: "MY", Name: "Malaysia", Flag: "🇲🇾"},
-	"malaysia": {Code: "MY", Name: "Malaysia", Flag: "🇲🇾"},
-	"马来西亚":     {Code: "MY", Name: "Malaysia", Flag: "🇲🇾"},
-
-	"th":       {Code: "TH", Name: "Thailand", Flag: "🇹🇭"},
-	"thailand": {Code: "TH", Name: "Thailand", Flag: "🇹🇭"},
-	"泰国":       {Code: "TH", Name: "Thailand", Flag: "🇹🇭"},
-	"曼谷":       {Code: "TH", Name: "Thailand", Flag: "🇹🇭"},
-
-	"vn":       {Code: "VN", Name: "Vietnam", Flag: "🇻🇳"},
-	"vietnam":  {Code: "VN", Name: "Vietnam", Flag: "🇻🇳"},
-	"越南":       {Code: "VN", Name: "Vietnam", Flag: "🇻🇳"},
-
-	"ph":          {Code: "PH", Name: "Philippines", Flag: "🇵🇭"},
-	"philippines": {Code: "PH", Name: "Philippines", Flag: "🇵🇭"},
-	"菲律宾":         {Code: "PH", Name: "Philippines", Flag: "🇵🇭"},
-
-	"id":        {Code: "ID", Name: "Indonesia", Flag: "🇮🇩"},
-	"indonesia": {Code: "ID", Name: "Indonesia", Flag: "🇮🇩"},
-	"印尼":        {Code: "ID", Name: "Indonesia", Flag: "🇮🇩"},
-	"印度尼西亚":     {Code: "ID", Name: "Indonesia", Flag: "🇮🇩"},
-
-	// North America
-	"us":      {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"usa":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"america": {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"美国":      {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"洛杉矶":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"纽约":      {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"西雅图":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"圣何塞":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"硅谷":      {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"达拉斯":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"芝加哥":     {Code: "US", Name: "United States", Flag: "🇺🇸"},
-
-	"ca":     {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-	"canada": {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-	"加拿大":    {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-	"多伦多":    {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-	"温哥华":    {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-
-	// Europe
-	"uk":      {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"gb":      {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"england": {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"britain": {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"英国":      {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"伦敦":      {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-
-	"de":      {Code: "DE", Name: "Germany", Flag: "🇩🇪"},
-	"germany": {Code: "DE", Name: "Germany", Flag: "🇩🇪"},
-	"德国":      {Code: "DE", Name: "Germany", Flag: "🇩🇪"},
-	"法兰克福":    {Code: "DE", Name: "Germany", Flag: "🇩🇪"},
-
-	"fr":     {Code: "FR", Name: "France", Flag: "🇫🇷"},
-	"france": {Code: "FR", Name: "France", Flag: "🇫🇷"},
-	"法国":     {Code: "FR", Name: "France", Flag: "🇫🇷"},
-	"巴黎":     {Code: "FR", Name: "France", Flag: "🇫🇷"},
-
-	"nl":          {Code: "NL", Name: "Netherlands", Flag: "🇳🇱"},
-	"netherlands": {Code: "NL", Name: "Netherlands", Flag: "🇳🇱"},
-	"荷兰":          {Code: "NL", Name: "Netherlands", Flag: "🇳🇱"},
-	"阿姆斯特丹":       {Code: "NL", Name: "Netherlands", Flag: "🇳🇱"},
+type subdivisionRecord struct {
+	Code     string   `json:"code"`
+	Name     string   `json:"name"`
+	Country  string   `json:"country"`
+	Keywords []string `json:"keywords"`
+}
 
-	"ru":     {Code: "RU", Name: "Russia", Flag: "🇷🇺"},
-	"russia": {Code: "RU", Name: "Russia", Flag: "🇷🇺"},
-	"俄罗斯":    {Code: "RU", Name: "Russia", Flag: "🇷🇺"},
-	"莫斯科":    {Code: "RU", Name: "Russia", Flag: "🇷🇺"},
+type cityRecord struct {
+	Name        string   `json:"name"`
+	Country     string   `json:"country"`
+	Subdivision string   `json:"subdivision"`
+	Lat         float64  `json:"lat"`
+	Lon         float64  `json:"lon"`
+	Keywords    []string `json:"keywords"`
+}
 
-	"it":    {Code: "IT", Name: "Italy", Flag: "🇮🇹"},
-	"italy": {Code: "IT", Name: "Italy", Flag: "🇮🇹"},
-	"意大利":   {Code: "IT", Name: "Italy", Flag: "🇮🇹"},
+type gazetteer struct {
+	Countries    []countryRecord     `json:"countries"`
+	Subdivisions []subdivisionRecord `json:"subdivisions"`
+	Cities       []cityRecord        `json:"cities"`
+}
 
-	"es":    {Code: "ES", Name: "Spain", Flag: "🇪🇸"},
-	"spain": {Code: "ES", Name: "Spain", Flag: "🇪🇸"},
-	"西班牙":   {Code: "ES", Name: "Spain", Flag: "🇪🇸"},
+// Subdivision is an ISO 3166-2 entry (a state, province, or similar)
+// exposed through GetSubdivisions for building region-picker UIs or
+// subdivision-level filtering policies.
+type Subdivision struct {
+	Code    string // ISO 3166-2, e.g. "US-CA"
+	Name    string // e.g. "California"
+	Country string // ISO 3166-1 alpha-2 parent, e.g. "US"
+}
 
-	"ch":          {Code: "CH", Name: "Switzerland", Flag: "🇨🇭"},
-	"switzerland": {Code: "CH", Name: "Switzerland", Flag: "🇨🇭"},
-	"瑞士":          {Code: "CH", Name: "Switzerland", Flag: "🇨🇭"},
+// City is a gazetteer city entry, exposed through GetCities.
+type City struct {
+	Name        string
+	Country     string
+	Subdivision string // ISO 3166-2 code, may be empty
+	Latitude    float64
+	Longitude   float64
+}
 
-	"se":     {Code: "SE", Name: "Sweden", Flag: "🇸🇪"},
-	"sweden": {Code: "SE", Name: "Sweden", Flag: "🇸🇪"},
-	"瑞典":     {Code: "SE", Name: "Sweden", Flag: "🇸🇪"},
+// matchTier ranks how specific a keyword is, so that when a node name
+// contains more than one match (e.g. both a country name and a city
+// within it) DetectRegion prefers the most specific one rather than
+// whichever the automaton happened to visit first.
+type matchTier int
+
+const (
+	tierCode        matchTier = iota + 1 // two-letter ISO code: shortest, most ambiguous
+	tierName                             // full country name, native or English
+	tierSubdivision                      // state/province name
+	tierCity                             // city name: most specific text match
+	tierFlag                             // emoji flag: unambiguous, effectively a code
+)
 
-	"fi":      {Code: "FI", Name: "Finland", Flag: "🇫🇮"},
-	"finland": {Code: "FI", Name: "Finland", Flag: "🇫🇮"},
-	"芬兰":      {Code: "FI", Name: "Finland", Flag: "🇫🇮"},
+type matchEntry struct {
+	tier   matchTier
+	region RegionInfo
+}
 
-	"no":     {Code: "NO", Name: "Norway", Flag: "🇳🇴"},
-	"norway": {Code: "NO", Name: "Norway", Flag: "🇳🇴"},
-	"挪威":     {Code: "NO", Name: "Norway", Flag: "🇳🇴"},
+var (
+	countriesByCode map[string]countryRecord
+	allSubdivisions []Subdivision
+	allCities       []City
+	matchPatterns   []string
+	matchEntries    []matchEntry
+	matcher         *ahoCorasick
+)
 
-	"pl":     {Code: "PL", Name: "Poland", Flag: "🇵🇱"},
-	"poland": {Code: "PL", Name: "Poland", Flag: "🇵🇱"},
-	"波兰":     {Code: "PL", Name: "Poland", Flag: "🇵🇱"},
+func init() {
+	var g gazetteer
+	if err := json.Unmarshal(gazetteerJSON, &g); err != nil {
+		panic("node: failed to parse embedded gazetteer.json: " + err.Error())
+	}
 
-	"tr":     {Code: "TR", Name: "Turkey", Flag: "🇹🇷"},
-	"turkey": {Code: "TR", Name: "Turkey", Flag: "🇹🇷"},
-	"土耳其":    {Code: "TR", Name: "Turkey", Flag: "🇹🇷"},
+	countriesByCode = make(map[string]countryRecord, len(g.Countries))
+	for _, c := range g.Countries {
+		countriesByCode[c.Code] = c
 
-	// Oceania
-	"au":        {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
-	"australia": {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
-	"澳大利亚":      {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
-	"悉尼":        {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
-	"墨尔本":       {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
+		base := RegionInfo{
+			Code: c.Code, Name: c.Name, Flag: c.Flag, Continent: c.Continent,
+			Latitude: c.Lat, Longitude: c.Lon,
+		}
+		for _, kw := range c.Codes {
+			addPattern(kw, tierCode, base)
+		}
+		for _, kw := range c.Names {
+			addPattern(kw, tierName, base)
+		}
+		if c.Flag != "" {
+			addPattern(c.Flag, tierFlag, base)
+		}
+	}
 
-	"nz":           {Code: "NZ", Name: "New Zealand", Flag: "🇳🇿"},
-	"new zealand":  {Code: "NZ", Name: "New Zealand", Flag: "🇳🇿"},
-	"newzealand":   {Code: "NZ", Name: "New Zealand", Flag: "🇳🇿"},
-	"新西兰":         {Code: "NZ", Name: "New Zealand", Flag: "🇳🇿"},
+	for _, sub := range g.Subdivisions {
+		allSubdivisions = append(allSubdivisions, Subdivision{Code: sub.Code, Name: sub.Name, Country: sub.Country})
 
-	// South America
-	"br":     {Code: "BR", Name: "Brazil", Flag: "🇧🇷"},
-	"brazil": {Code: "BR", Name: "Brazil", Flag: "🇧🇷"},
-	"巴西":     {Code: "BR", Name: "Brazil", Flag: "🇧🇷"},
+		country := countriesByCode[sub.Country]
+		info := RegionInfo{
+			Code: country.Code, Name: country.Name, Flag: country.Flag, Continent: country.Continent,
+			Subdivision: sub.Code, Latitude: country.Lat, Longitude: country.Lon,
+		}
+		for _, kw := range sub.Keywords {
+			addPattern(kw, tierSubdivision, info)
+		}
+	}
 
-	"ar":        {Code: "AR", Name: "Argentina", Flag: "🇦🇷"},
-	"argentina": {Code: "AR", Name: "Argentina", Flag: "🇦🇷"},
-	"阿根廷":       {Code: "AR", Name: "Argentina", Flag: "🇦🇷"},
+	for _, city := range g.Cities {
+		country := countriesByCode[city.Country]
+		allCities = append(allCities, City{
+			Name: city.Name, Country: city.Country, Subdivision: city.Subdivision,
+			Latitude: city.Lat, Longitude: city.Lon,
+		})
+
+		info := RegionInfo{
+			Code: country.Code, Name: country.Name, Flag: country.Flag, Continent: country.Continent,
+			Subdivision: city.Subdivision, City: city.Name,
+			Latitude: city.Lat, Longitude: city.Lon,
+		}
+		for _, kw := range city.Keywords {
+			addPattern(kw, tierCity, info)
+		}
+	}
 
-	// Middle East
-	"ae":  {Code: "AE", Name: "UAE", Flag: "🇦🇪"},
-	"uae": {Code: "AE", Name: "UAE", Flag: "🇦🇪"},
-	"阿联酋": {Code: "AE", Name: "UAE", Flag: "🇦🇪"},
-	"迪拜":  {Code: "AE", Name: "UAE", Flag: "🇦🇪"},
+	matcher = newAhoCorasick(matchPatterns)
+}
 
-	"il":     {Code: "IL", Name: "Israel", Flag: "🇮🇱"},
-	"israel": {Code: "IL", Name: "Israel", Flag: "🇮🇱"},
-	"以色列":    {Code: "IL", Name: "Israel", Flag: "🇮🇱"},
+func addPattern(keyword string, tier matchTier, info RegionInfo) {
+	matchPatterns = append(matchPatterns, strings.ToLower(keyword))
+	matchEntries = append(matchEntries, matchEntry{tier: tier, region: info})
+}
 
-	// South Asia
-	"in":    {Code: "IN", Name: "India", Flag: "🇮🇳"},
-	"india": {Code: "IN", Name: "India", Flag: "🇮🇳"},
-	"印度":    {Code: "IN", Name: "India", Flag: "🇮🇳"},
+// DetectRegion attempts to identify the region from a node's name or URI
+// using the package's default RegionDetector (see SetDefaultDetector). The
+// default detector runs every known keyword, country name, and city name
+// against name in a single Aho-Corasick pass, then keeps the most specific
+// hit: a city match beats a country name, which beats a bare two-letter
+// code, since the latter is the most likely to appear as a coincidental
+// substring. Among equally specific hits it keeps the longest keyword, and
+// it breaks any remaining tie using gazetteer.json's listing order for
+// determinism.
+func DetectRegion(nodeName string) RegionInfo {
+	return defaultDetector.Detect(nodeName)
 }
 
-// flagToRegion maps emoji flags to region codes
-var flagToRegion = map[string]RegionInfo{
-	"🇭🇰": {Code: "HK", Name: "Hong Kong", Flag: "🇭🇰"},
-	"🇹🇼": {Code: "TW", Name: "Taiwan", Flag: "🇹🇼"},
-	"🇯🇵": {Code: "JP", Name: "Japan", Flag: "🇯🇵"},
-	"🇰🇷": {Code: "KR", Name: "South Korea", Flag: "🇰🇷"},
-	"🇨🇳": {Code: "CN", Name: "China", Flag: "🇨🇳"},
-	"🇸🇬": {Code: "SG", Name: "Singapore", Flag: "🇸🇬"},
-	"🇲🇾": {Code: "MY", Name: "Malaysia", Flag: "🇲🇾"},
-	"🇹🇭": {Code: "TH", Name: "Thailand", Flag: "🇹🇭"},
-	"🇻🇳": {Code: "VN", Name: "Vietnam", Flag: "🇻🇳"},
-	"🇵🇭": {Code: "PH", Name: "Philippines", Flag: "🇵🇭"},
-	"🇮🇩": {Code: "ID", Name: "Indonesia", Flag: "🇮🇩"},
-	"🇺🇸": {Code: "US", Name: "United States", Flag: "🇺🇸"},
-	"🇨🇦": {Code: "CA", Name: "Canada", Flag: "🇨🇦"},
-	"🇬🇧": {Code: "GB", Name: "United Kingdom", Flag: "🇬🇧"},
-	"🇩🇪": {Code: "DE", Name: "Germany", Flag: "🇩🇪"},
-	"🇫🇷": {Code: "FR", Name: "France", Flag: "🇫🇷"},
-	"🇳🇱": {Code: "NL", Name: "Netherlands", Flag: "🇳🇱"},
-	"🇷🇺": {Code: "RU", Name: "Russia", Flag: "🇷🇺"},
-	"🇮🇹": {Code: "IT", Name: "Italy", Flag: "🇮🇹"},
-	"🇪🇸": {Code: "ES", Name: "Spain", Flag: "🇪🇸"},
-	"🇨🇭": {Code: "CH", Name: "Switzerland", Flag: "🇨🇭"},
-	"🇸🇪": {Code: "SE", Name: "Sweden", Flag: "🇸🇪"},
-	"🇫🇮": {Code: "FI", Name: "Finland", Flag: "🇫🇮"},
-	"🇳🇴": {Code: "NO", Name: "Norway", Flag: "🇳🇴"},
-	"🇵🇱": {Code: "PL", Name: "Poland", Flag: "🇵🇱"},
-	"🇹🇷": {Code: "TR", Name: "Turkey", Flag: "🇹🇷"},
-	"🇦🇺": {Code: "AU", Name: "Australia", Flag: "🇦🇺"},
-	"🇳🇿": {Code: "NZ", Name: "New Zealand", Flag: "🇳🇿"},
-	"🇧🇷": {Code: "BR", Name: "Brazil", Flag: "🇧🇷"},
-	"🇦🇷": {Code: "AR", Name: "Argentina", Flag: "🇦🇷"},
-	"🇦🇪": {Code: "AE", Name: "UAE", Flag: "🇦🇪"},
-	"🇮🇱": {Code: "IL", Name: "Israel", Flag: "🇮🇱"},
-	"🇮🇳": {Code: "IN", Name: "India", Flag: "🇮🇳"},
+// DetectRegionForHost is DetectRegion for callers that also have the
+// node's actual server address (host, a hostname or IP literal) on hand.
+// When the installed detector is host-aware (a *ChainDetector wrapping a
+// GeoIP fallback, via SetDefaultDetector), host is what its IP fallback
+// resolves instead of nodeName — letting it place nodes whose display
+// name is an opaque label like "vmess-042" that will never resolve as a
+// DNS name. Detectors that aren't host-aware just ignore host.
+func DetectRegionForHost(nodeName, host string) RegionInfo {
+	if hd, ok := defaultDetector.(HostAwareDetector); ok {
+		return hd.DetectHost(nodeName, host)
+	}
+	return defaultDetector.Detect(nodeName)
 }
 
-// DetectRegion attempts to identify the region from node name or URI
-func DetectRegion(nodeName string) RegionInfo {
+// matchKeywords is the keyword/gazetteer matching logic shared by
+// keywordDetector and DetectRegion.
+func matchKeywords(nodeName string) RegionInfo {
 	if nodeName == "" {
 		return RegionInfo{}
 	}
 
 	nameLower := strings.ToLower(nodeName)
-
-	// First, check for emoji flags by direct string matching
-	for flag, info := range flagToRegion {
-		if strings.Contains(nodeName, flag) {
-			return info
-		}
+	matches := matcher.findAll(nameLower)
+	if len(matches) == 0 {
+		return RegionInfo{}
 	}
 
-	// Then check for keywords
-	for keyword, info := range regionPatterns {
-		if strings.Contains(nameLower, keyword) {
-			return info
+	best := matches[0]
+	for _, idx := range matches[1:] {
+		if betterMatch(idx, best) {
+			best = idx
 		}
 	}
-
-	return RegionInfo{}
+	return matchEntries[best].region
 }
 
+func betterMatch(candidate, current int) bool {
+	ct, cc := matchEntries[candidate].tier, matchEntries[current].tier
+	if ct != cc {
+		return ct > cc
+	}
+	cl, bl := len(matchPatterns[candidate]), len(matchPatterns[current])
+	if cl != bl {
+		return cl > bl
+	}
+	return candidate < current
+}
 
-// GetAllRegions returns a list of all known regions
+// GetAllRegions returns one RegionInfo per known country.
 func GetAllRegions() []RegionInfo {
-	seen := make(map[string]bool)
-	var result []RegionInfo
+	result := make([]RegionInfo, 0, len(countriesByCode))
+	for _, c := range countriesByCode {
+		result = append(result, RegionInfo{
+			Code: c.Code, Name: c.Name, Flag: c.Flag, Continent: c.Continent,
+			Latitude: c.Lat, Longitude: c.Lon,
+		})
+	}
+	return result
+}
 
-	for _, info := range regionPatterns {
-		if !seen[info.Code] {
-			seen[info.Code] = true
-			result = append(result, info)
+// GetSubdivisions returns every known ISO 3166-2 subdivision of
+// countryCode (e.g. "US" -> California, Texas, ...), letting callers build
+// region-picker UIs or filter policies finer than country granularity.
+func GetSubdivisions(countryCode string) []Subdivision {
+	countryCode = strings.ToUpper(countryCode)
+	var result []Subdivision
+	for _, sub := range allSubdivisions {
+		if sub.Country == countryCode {
+			result = append(result, sub)
 		}
 	}
+	return result
+}
 
+// GetCities returns every known city belonging to countryOrSubdivisionCode,
+// which may be either an ISO 3166-1 country code (e.g. "US") or an ISO
+// 3166-2 subdivision code (e.g. "US-CA").
+func GetCities(countryOrSubdivisionCode string) []City {
+	code := strings.ToUpper(countryOrSubdivisionCode)
+	var result []City
+	for _, city := range allCities {
+		if city.Country == code || city.Subdivision == code {
+			result = append(result, city)
+		}
+	}
 	return result
 }
@@ -0,0 +1,316 @@
+// Package peering replicates subscriptions and node health between
+// easy_proxies instances, the way Consul's cluster peering lets two
+// datacenters share service state. Each registered store.Peer gets its own
+// long-lived HTTP stream: this instance POSTs its local store.Watch events
+// to the peer's StreamPath as newline-delimited JSON over a chunked
+// request body, and the peer does the same back, so neither side needs a
+// request/response round trip per event.
+package peering
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"easy_proxies/internal/store"
+)
+
+// StreamPath is the HTTP endpoint a peer's Manager dials to receive this
+// instance's events. app.go wires it to Manager.ServeStream alongside the
+// rest of the monitor server's routes.
+const StreamPath = "/api/peering/stream"
+
+// reconnectDelay bounds the backoff between dial attempts after a peer
+// connection drops; reconcileInterval bounds how quickly a newly
+// store.AddPeer'd peer gets picked up.
+const (
+	reconnectDelay    = 5 * time.Second
+	reconcileInterval = 10 * time.Second
+)
+
+// wireEvent is the JSON shape exchanged over a peering stream: enough of a
+// store.Event to replay it on the other side, plus the sending instance's
+// ID for GuaranteedUpdateNode's last-writer-wins tiebreaker.
+type wireEvent struct {
+	Type         store.EventType     `json:"type"`
+	Node         *store.EnhancedNode `json:"node,omitempty"`
+	Subscription *store.Subscription `json:"subscription,omitempty"`
+	At           time.Time           `json:"at"`
+	OriginID     string              `json:"origin_id"`
+}
+
+// Manager owns this instance's peering connections: one outbound dial per
+// registered store.Peer, plus ServeStream for the inbound side other
+// instances' dials land on. It implements supervise.Service.
+type Manager struct {
+	st     *store.Store
+	selfID string
+	client *http.Client
+}
+
+// New creates a Manager for st. selfID identifies this instance in every
+// event it emits, and breaks ties when GuaranteedUpdateNode's
+// last-writer-wins comparison finds two peers reporting the exact same
+// wall-clock timestamp.
+func New(st *store.Store, selfID string) *Manager {
+	return &Manager{st: st, selfID: selfID, client: &http.Client{}}
+}
+
+// Serve reconciles the set of registered peers onto a live goroutine each
+// every reconcileInterval, so peers added or removed through the
+// AddPeer/DeletePeer API take effect without a restart, and keeps running
+// until ctx is done.
+func (m *Manager) Serve(ctx context.Context) error {
+	active := make(map[string]context.CancelFunc)
+	defer func() {
+		for _, cancel := range active {
+			cancel()
+		}
+	}()
+
+	reconcile := func() {
+		seen := make(map[string]bool)
+		for _, peer := range m.st.ListPeers() {
+			seen[peer.ID] = true
+			if _, ok := active[peer.ID]; ok {
+				continue
+			}
+			peerCtx, cancel := context.WithCancel(ctx)
+			active[peer.ID] = cancel
+			go m.maintain(peerCtx, peer)
+		}
+		for id, cancel := range active {
+			if !seen[id] {
+				cancel()
+				delete(active, id)
+			}
+		}
+	}
+
+	reconcile()
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			reconcile()
+		}
+	}
+}
+
+// Stop is a no-op: Serve's deferred cancels tear down every connection when
+// ctx is done.
+func (m *Manager) Stop() {}
+
+// maintain keeps a single peer connection open, recording its status after
+// every attempt and reconnecting with reconnectDelay in between.
+func (m *Manager) maintain(ctx context.Context, peer *store.Peer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		start := time.Now()
+		err := m.stream(ctx, peer)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		_ = m.st.UpdatePeerStatus(peer.ID, err == nil, time.Now(), time.Since(start), errMsg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// stream opens one long-lived connection to peer and forwards local store
+// events to it until the connection breaks or ctx is canceled.
+func (m *Manager) stream(ctx context.Context, peer *store.Peer) error {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer.Address+StreamPath, pr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+peer.Token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	events := m.st.Watch(ctx, store.EventFilter{})
+	done := make(chan error, 1)
+	go func() {
+		enc := json.NewEncoder(pw)
+		for {
+			select {
+			case <-ctx.Done():
+				pw.Close()
+				done <- nil
+				return
+			case evt, ok := <-events:
+				if !ok {
+					pw.Close()
+					done <- nil
+					return
+				}
+				wire := wireEvent{Type: evt.Type, Node: evt.Node, Subscription: evt.Subscription, At: evt.At, OriginID: m.selfID}
+				if err := enc.Encode(wire); err != nil {
+					pw.CloseWithError(err)
+					done <- err
+					return
+				}
+			}
+		}
+	}()
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peer returned %s", resp.Status)
+	}
+
+	return <-done
+}
+
+// ServeStream is the inbound HTTP handler a peer's dial lands on: it
+// authenticates the request against every registered peer's token, then
+// applies each decoded wireEvent locally.
+func (m *Manager) ServeStream(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	var matched *store.Peer
+	for _, peer := range m.st.ListPeers() {
+		if peer.Token == token {
+			matched = peer
+			break
+		}
+	}
+	if matched == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	dec := json.NewDecoder(r.Body)
+	for {
+		var evt wireEvent
+		if err := dec.Decode(&evt); err != nil {
+			break
+		}
+		m.apply(evt)
+	}
+
+	_ = m.st.UpdatePeerStatus(matched.ID, true, time.Now(), 0, "")
+}
+
+// apply replays one inbound wireEvent against the local store, resolving
+// node conflicts through GuaranteedUpdateNode and subscription conflicts
+// through applySubscriptionUpdate, so a concurrent local write can't be
+// silently clobbered by a stale peer event and a replicated write can't
+// echo between two peers forever.
+func (m *Manager) apply(evt wireEvent) {
+	switch evt.Type {
+	case store.EventNodeUpdated:
+		m.applyNodeUpdate(evt)
+
+	case store.EventNodeRemoved:
+		if evt.Node != nil {
+			m.st.RemoveNodeState(nodeKey(evt.Node))
+		}
+
+	case store.EventSubscriptionUpdated:
+		m.applySubscriptionUpdate(evt)
+
+	case store.EventSubscriptionRemoved:
+		m.applySubscriptionRemoved(evt)
+	}
+}
+
+// applySubscriptionUpdate mirrors applyNodeUpdate's last-writer-wins
+// conflict resolution for subscriptions. Without it, two peers echo an
+// add/update back and forth forever: applying an inbound event publishes
+// a local EventSubscriptionUpdated, which this instance's own stream then
+// forwards straight back out to the peer it came from. Comparing
+// UpdatedAt (and, on an exact tie, origin IDs, the same as nodes) lets the
+// losing side of each hop reject the replay instead of reapplying it.
+func (m *Manager) applySubscriptionUpdate(evt wireEvent) {
+	if evt.Subscription == nil {
+		return
+	}
+	incoming := evt.Subscription
+	if current, err := m.st.GetSubscription(incoming.ID); err == nil {
+		if !lastWriterWins(current.UpdatedAt, incoming.UpdatedAt, m.selfID, evt.OriginID) {
+			return
+		}
+	}
+	_ = m.st.ApplySubscriptionUpdate(incoming)
+}
+
+// applySubscriptionRemoved deletes a subscription a peer removed. A
+// previously-applied delete leaves GetSubscription returning
+// ErrSubscriptionNotFound, so a redundant replay is a no-op here and
+// DeleteSubscription never re-publishes: the loop stops on its own
+// without needing a timestamp check the way updates do.
+func (m *Manager) applySubscriptionRemoved(evt wireEvent) {
+	if evt.Subscription == nil {
+		return
+	}
+	_ = m.st.DeleteSubscription(evt.Subscription.ID)
+}
+
+func (m *Manager) applyNodeUpdate(evt wireEvent) {
+	if evt.Node == nil {
+		return
+	}
+	incoming := evt.Node
+	name := nodeKey(incoming)
+
+	err := m.st.GuaranteedUpdateNode(name, func(current *store.EnhancedNode) (*store.EnhancedNode, error) {
+		if !lastWriterWins(current.LastCheckAt, incoming.LastCheckAt, m.selfID, evt.OriginID) {
+			return nil, store.ErrConflict
+		}
+		merged := *incoming
+		return &merged, nil
+	})
+	if err == store.ErrNodeNotFound {
+		m.st.UpdateNodeState(incoming)
+	}
+}
+
+// nodeKey mirrors the Name-or-URI keying store.Store itself uses.
+func nodeKey(node *store.EnhancedNode) string {
+	if node.Name != "" {
+		return node.Name
+	}
+	return node.URI
+}
+
+// lastWriterWins reports whether an incoming update should replace the
+// current value: a strictly later wall-clock timestamp wins outright; an
+// exact tie (two peers reporting the identical timestamp) falls back to
+// comparing instance IDs, so both sides of the tie converge on the same
+// winner rather than flip-flopping forever.
+func lastWriterWins(currentAt, incomingAt time.Time, selfID, originID string) bool {
+	if incomingAt.After(currentAt) {
+		return true
+	}
+	if incomingAt.Before(currentAt) {
+		return false
+	}
+	return originID > selfID
+}
@@ -1,7 +1,9 @@
 package proxypool
 
 import (
+	"context"
 	"errors"
+	"math"
 	"math/rand"
 	"sort"
 	"sync"
@@ -18,14 +20,20 @@ type ProxyPool struct {
 	mode         store.PoolMode
 	nodes        []*store.EnhancedNode
 	currentIndex atomic.Int64
-	
+
 	// Weighted mode state
-	weights      map[string]int // node name -> weight
-	totalWeight  int
+	weights     map[string]int // node name -> weight
+	totalWeight int
+
+	// PoolModeP2C feedback, keyed by node name
+	runtimesMu sync.Mutex
+	runtimes   map[string]*nodeRuntime
 
 	// Settings
 	fallbackEnabled bool
 	apiKey          string
+
+	cancel context.CancelFunc
 }
 
 // Config for proxy pool
@@ -45,11 +53,93 @@ func NewProxyPool(s *store.Store, cfg Config) *ProxyPool {
 		store:           s,
 		mode:            cfg.Mode,
 		weights:         make(map[string]int),
+		runtimes:        make(map[string]*nodeRuntime),
 		fallbackEnabled: cfg.FallbackEnabled,
 		apiKey:          cfg.APIKey,
 	}
 }
 
+// ewmaAlpha weights ReportResult's latest observation against nodeRuntime's
+// running average; failHalfLife bounds how long a burst of failures keeps
+// depressing a node's score once it recovers.
+const (
+	ewmaAlpha    = 0.2
+	failHalfLife = 30 * time.Second
+)
+
+// nodeRuntime tracks PoolModeP2C's per-node online feedback: requests
+// currently in flight, an EWMA of observed latency, and a decayed recent
+// failure rate. Unlike EnhancedNode this is pool-local and never persisted
+// — it resets with the process, which is fine since it only needs to
+// reflect recent behavior.
+type nodeRuntime struct {
+	inFlight atomic.Int64
+
+	mu          sync.Mutex
+	ewmaLatency float64
+	failScore   float64
+	lastReport  time.Time
+}
+
+// report folds one proxied request's outcome into the node's EWMA latency
+// and failure score.
+func (nr *nodeRuntime) report(latencyMs int64, ok bool) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	if nr.ewmaLatency == 0 {
+		nr.ewmaLatency = float64(latencyMs)
+	} else {
+		nr.ewmaLatency = ewmaAlpha*float64(latencyMs) + (1-ewmaAlpha)*nr.ewmaLatency
+	}
+
+	now := time.Now()
+	if !nr.lastReport.IsZero() {
+		elapsed := now.Sub(nr.lastReport)
+		nr.failScore *= math.Pow(0.5, elapsed.Seconds()/failHalfLife.Seconds())
+	}
+	failVal := 0.0
+	if !ok {
+		failVal = 1.0
+	}
+	nr.failScore = ewmaAlpha*failVal + (1-ewmaAlpha)*nr.failScore
+	nr.lastReport = now
+}
+
+// cost is lower for nodes p2c should prefer: fast, lightly loaded, and not
+// recently failing.
+func (nr *nodeRuntime) cost() float64 {
+	nr.mu.Lock()
+	latency := nr.ewmaLatency
+	failScore := nr.failScore
+	nr.mu.Unlock()
+
+	if latency <= 0 {
+		latency = 1 // no observations yet; let in-flight/failure still differentiate
+	}
+	inFlight := float64(nr.inFlight.Load())
+	return latency * (1 + inFlight) * (1 + failScore)
+}
+
+// runtimeFor returns name's nodeRuntime, creating one on first use.
+func (p *ProxyPool) runtimeFor(name string) *nodeRuntime {
+	p.runtimesMu.Lock()
+	defer p.runtimesMu.Unlock()
+	rt, ok := p.runtimes[name]
+	if !ok {
+		rt = &nodeRuntime{}
+		p.runtimes[name] = rt
+	}
+	return rt
+}
+
+// ReportResult feeds the outcome of a proxied request through node back
+// into PoolModeP2C's cost calculation. Safe to call for any node name,
+// even one outside the currently filtered set.
+func (p *ProxyPool) ReportResult(node string, latencyMs int64, ok bool) {
+	p.runtimeFor(node).report(latencyMs, ok)
+}
+
 // SetMode changes the rotation mode
 func (p *ProxyPool) SetMode(mode store.PoolMode) {
 	p.mu.Lock()
@@ -64,7 +154,10 @@ func (p *ProxyPool) GetMode() store.PoolMode {
 	return p.mode
 }
 
-// RefreshNodes updates the internal node list from store
+// RefreshNodes rebuilds the internal node list from store in one shot.
+// Start calls this once for the initial snapshot; after that,
+// applyNodeEvent keeps nodes/weights current incrementally as store
+// events arrive, so callers no longer need to call RefreshNodes themselves.
 func (p *ProxyPool) RefreshNodes() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -73,6 +166,76 @@ func (p *ProxyPool) RefreshNodes() {
 	p.updateWeights()
 }
 
+// Start loads the current node snapshot and subscribes to store events, so
+// the pool keeps itself in sync as nodes come up, go down, or are removed
+// without anything explicitly calling RefreshNodes again. The subscription
+// is torn down when ctx is canceled or Stop is called.
+func (p *ProxyPool) Start(ctx context.Context) {
+	p.RefreshNodes()
+
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+
+	events := p.store.Watch(ctx, store.EventFilter{
+		Types: []store.EventType{store.EventNodeUpdated, store.EventNodeRemoved},
+	})
+	go func() {
+		for evt := range events {
+			p.applyNodeEvent(evt)
+		}
+	}()
+}
+
+// Stop ends the background subscription started by Start.
+func (p *ProxyPool) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// nodeKey returns the key a node is indexed under in store.Store, so
+// applyNodeEvent can find the matching entry in p.nodes.
+func nodeKey(node *store.EnhancedNode) string {
+	if node.Name != "" {
+		return node.Name
+	}
+	return node.URI
+}
+
+// applyNodeEvent folds a single node update/removal into p.nodes, keeping
+// only enabled and available nodes — the same set RefreshNodes would have
+// pulled from store.ListAvailableNodes.
+func (p *ProxyPool) applyNodeEvent(evt store.Event) {
+	if evt.Node == nil {
+		return
+	}
+	key := nodeKey(evt.Node)
+	include := evt.Type == store.EventNodeUpdated && evt.Node.Status == store.NodeStatusEnabled && evt.Node.Available
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := -1
+	for i, n := range p.nodes {
+		if nodeKey(n) == key {
+			idx = i
+			break
+		}
+	}
+
+	switch {
+	case idx >= 0 && include:
+		p.nodes[idx] = evt.Node
+	case idx >= 0 && !include:
+		p.nodes = append(p.nodes[:idx], p.nodes[idx+1:]...)
+	case idx < 0 && include:
+		p.nodes = append(p.nodes, evt.Node)
+	default:
+		return
+	}
+	p.updateWeights()
+}
+
 // updateWeights recalculates weights based on latency
 func (p *ProxyPool) updateWeights() {
 	p.weights = make(map[string]int)
@@ -115,10 +278,13 @@ type Filter struct {
 	Subscription string
 }
 
-// GetProxy returns a proxy based on current mode and optional filters
-func (p *ProxyPool) GetProxy(filter *Filter) (*store.EnhancedNode, error) {
+// GetProxy returns a proxy based on current mode and optional filters. The
+// returned release func must be called once the caller is done with the
+// proxy (e.g. the proxied request finished) so PoolModeP2C's in-flight
+// count stays accurate; it's always safe to call, even for modes that
+// don't use it.
+func (p *ProxyPool) GetProxy(filter *Filter) (*store.EnhancedNode, func(), error) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
 
 	// Get filtered nodes
 	nodes := p.getFilteredNodes(filter)
@@ -129,21 +295,55 @@ func (p *ProxyPool) GetProxy(filter *Filter) (*store.EnhancedNode, error) {
 			nodes = p.getFallbackNodes(filter)
 		}
 		if len(nodes) == 0 {
-			return nil, ErrNoAvailableProxy
+			p.mu.RUnlock()
+			return nil, func() {}, ErrNoAvailableProxy
 		}
 	}
 
 	// Select based on mode
+	var node *store.EnhancedNode
 	switch p.mode {
 	case store.PoolModeRandom:
-		return p.selectRandom(nodes), nil
+		node = p.selectRandom(nodes)
 	case store.PoolModeLatencyFirst:
-		return p.selectLatencyFirst(nodes), nil
+		node = p.selectLatencyFirst(nodes)
 	case store.PoolModeWeighted:
-		return p.selectWeighted(nodes), nil
+		node = p.selectWeighted(nodes)
+	case store.PoolModeP2C:
+		node = p.selectP2C(nodes)
 	default: // Sequential
-		return p.selectSequential(nodes), nil
+		node = p.selectSequential(nodes)
+	}
+	p.mu.RUnlock()
+
+	rt := p.runtimeFor(node.Name)
+	rt.inFlight.Add(1)
+	return node, func() { rt.inFlight.Add(-1) }, nil
+}
+
+// selectP2C implements power-of-two-choices: sample two distinct
+// candidates uniformly at random and return whichever has the lower
+// nodeRuntime cost, avoiding the herd effect a strict "always pick the
+// best" strategy causes under concurrent load.
+func (p *ProxyPool) selectP2C(nodes []*store.EnhancedNode) *store.EnhancedNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	i := rand.Intn(len(nodes))
+	j := rand.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := nodes[i], nodes[j]
+	if p.runtimeFor(a.Name).cost() <= p.runtimeFor(b.Name).cost() {
+		return a
 	}
+	return b
 }
 
 // GetProxyList returns multiple proxies based on filters
@@ -158,26 +358,40 @@ func (p *ProxyPool) GetProxyList(filter *Filter, limit int) []*store.EnhancedNod
 	return nodes
 }
 
-// getFilteredNodes returns nodes matching the filter
+// getFilteredNodes returns nodes matching the filter. Subscription can
+// match either a subscription ID or name, which isn't something a single
+// store index covers, so that case still filters p.nodes in-process;
+// Region/LatencyLevel alone go straight to store.Query's pre-filtered,
+// index-backed slice instead of a linear scan over every node.
 func (p *ProxyPool) getFilteredNodes(filter *Filter) []*store.EnhancedNode {
 	if filter == nil {
 		return p.nodes
 	}
 
-	var result []*store.EnhancedNode
-	for _, node := range p.nodes {
-		if filter.LatencyLevel != "" && node.LatencyLevel != filter.LatencyLevel {
-			continue
-		}
-		if filter.Region != "" && node.Region != filter.Region {
-			continue
+	if filter.Subscription != "" {
+		var result []*store.EnhancedNode
+		for _, node := range p.nodes {
+			if filter.LatencyLevel != "" && node.LatencyLevel != filter.LatencyLevel {
+				continue
+			}
+			if filter.Region != "" && node.Region != filter.Region {
+				continue
+			}
+			if node.SubscriptionID != filter.Subscription && node.SubscriptionName != filter.Subscription {
+				continue
+			}
+			result = append(result, node)
 		}
-		if filter.Subscription != "" && node.SubscriptionID != filter.Subscription && node.SubscriptionName != filter.Subscription {
-			continue
-		}
-		result = append(result, node)
+		return result
 	}
-	return result
+
+	return p.store.Query(store.NodeFilter{
+		Region:       filter.Region,
+		LatencyLevel: filter.LatencyLevel,
+		Status:       store.NodeStatusEnabled,
+		AvailableSet: true,
+		Available:    true,
+	})
 }
 
 // getFallbackNodes returns nodes with relaxed filters for fallback
@@ -230,7 +444,7 @@ func (p *ProxyPool) selectLatencyFirst(nodes []*store.EnhancedNode) *store.Enhan
 	if len(nodes) == 0 {
 		return nil
 	}
-	
+
 	// Sort by latency
 	sorted := make([]*store.EnhancedNode, len(nodes))
 	copy(sorted, nodes)
@@ -306,11 +520,9 @@ func (p *ProxyPool) SetAPIKey(key string) {
 	p.apiKey = key
 }
 
-// Stats returns pool statistics
+// Stats returns pool statistics. nodes/weights are kept current by Start's
+// background subscription, so this just reads the snapshot.
 func (p *ProxyPool) Stats() PoolStats {
-	// Refresh nodes first to ensure up-to-date data
-	p.RefreshNodes()
-
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
@@ -0,0 +1,170 @@
+package proxypool
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"easy_proxies/internal/store"
+)
+
+// SelectionStrategy names one of the per-request candidate-picking
+// algorithms available on /api/proxy/get via the strategy= query param,
+// independent of the pool-wide rotation Mode used by GetProxy.
+type SelectionStrategy string
+
+const (
+	StrategyLowestLatency   SelectionStrategy = "lowest_latency"
+	StrategyRoundRobin      SelectionStrategy = "round_robin"
+	StrategyRandom          SelectionStrategy = "random"
+	StrategyWeightedLatency SelectionStrategy = "weighted_latency"
+)
+
+// resortInterval and resortEvery bound how often Selector re-sorts its
+// latency-ordered candidate cache, so a large pool isn't sorted on every
+// single request.
+const (
+	resortInterval = time.Second
+	resortEvery    = 50
+)
+
+// Selector picks one node from a candidate slice according to a
+// SelectionStrategy. It holds strategy-specific state (round-robin cursor,
+// a periodically-resorted latency cache) so repeated calls for the same
+// strategy don't redo expensive work on every request.
+type Selector struct {
+	strategy SelectionStrategy
+
+	rrIndex atomic.Uint64
+
+	cacheMu   sync.Mutex
+	cached    []*store.EnhancedNode
+	cachedSig uint64
+	cachedAt  time.Time
+	picks     int64
+}
+
+// NewSelector creates a Selector for the given strategy.
+func NewSelector(strategy SelectionStrategy) *Selector {
+	return &Selector{strategy: strategy}
+}
+
+// Select picks one node from nodes according to s.strategy. Returns nil if
+// nodes is empty.
+func (s *Selector) Select(nodes []*store.EnhancedNode) *store.EnhancedNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	switch s.strategy {
+	case StrategyRoundRobin:
+		idx := s.rrIndex.Add(1) - 1
+		return nodes[idx%uint64(len(nodes))]
+
+	case StrategyRandom:
+		return nodes[rand.Intn(len(nodes))]
+
+	case StrategyWeightedLatency:
+		return s.selectWeightedLatency(nodes)
+
+	default: // StrategyLowestLatency
+		return s.selectLowestLatency(nodes)
+	}
+}
+
+// selectLowestLatency returns the best of a latency-sorted cache, refreshed
+// at most once per resortInterval or every resortEvery picks.
+func (s *Selector) selectLowestLatency(nodes []*store.EnhancedNode) *store.EnhancedNode {
+	sorted := s.sortedByLatency(nodes)
+	if len(sorted) == 0 {
+		return nil
+	}
+	return sorted[0]
+}
+
+// selectWeightedLatency picks with probability proportional to 1/latency;
+// nodes with unknown (<=0) latency get a minimal fallback weight, equal to
+// that of the slowest known node.
+func (s *Selector) selectWeightedLatency(nodes []*store.EnhancedNode) *store.EnhancedNode {
+	weights := make([]float64, len(nodes))
+	minWeight := 1.0 // kept when no node has a known latency
+	for i, n := range nodes {
+		if n.Latency > 0 {
+			weights[i] = 1.0 / float64(n.Latency)
+			minWeight = math.Min(minWeight, weights[i])
+		}
+	}
+	var total float64
+	for i, n := range nodes {
+		if n.Latency <= 0 {
+			weights[i] = minWeight
+		}
+		total += weights[i]
+	}
+
+	r := rand.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r <= 0 {
+			return nodes[i]
+		}
+	}
+	return nodes[len(nodes)-1]
+}
+
+// sortedByLatency returns nodes sorted by ascending latency (unknown
+// latency last), rebuilding the cache only every resortInterval or
+// resortEvery picks. The Selector is shared across every call for its
+// strategy (see ProxyPoolHandler.selectorFor), and callers pass in
+// different candidate subsets — e.g. the same "lowest_latency" Selector
+// sees one caller's region=US nodes and another's region=JP nodes — so
+// the cache is keyed on a signature of the passed-in nodes, not just time
+// elapsed; otherwise a resort triggered by one candidate set would be
+// handed back, unsorted-for-its-purpose, to a caller with a different one.
+func (s *Selector) sortedByLatency(nodes []*store.EnhancedNode) []*store.EnhancedNode {
+	sig := nodeSetSignature(nodes)
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	s.picks++
+	if s.cached != nil && s.cachedSig == sig && time.Since(s.cachedAt) < resortInterval && s.picks%resortEvery != 0 {
+		return s.cached
+	}
+
+	sorted := make([]*store.EnhancedNode, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Latency <= 0 {
+			return false
+		}
+		if sorted[j].Latency <= 0 {
+			return true
+		}
+		return sorted[i].Latency < sorted[j].Latency
+	})
+
+	s.cached = sorted
+	s.cachedSig = sig
+	s.cachedAt = time.Now()
+	return sorted
+}
+
+// nodeSetSignature hashes the names and latencies of a candidate slice so
+// sortedByLatency's cache can tell two different candidate sets apart
+// without doing a full sort to compare them.
+func nodeSetSignature(nodes []*store.EnhancedNode) uint64 {
+	h := fnv.New64a()
+	for _, n := range nodes {
+		h.Write([]byte(n.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.FormatInt(n.Latency, 10)))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,67 @@
+package store
+
+// Collection names the logical record groups Store persists. Each backend
+// keys its storage (a bucket, a hash, a file) by Collection so unrelated
+// records never collide.
+type Collection string
+
+const (
+	CollectionSubscriptions Collection = "subscriptions"
+	CollectionNodeStates    Collection = "node_states"
+	CollectionWebhooks      Collection = "webhooks"
+	CollectionDeliveries    Collection = "deliveries"
+	CollectionMeta          Collection = "meta"
+	CollectionPeers         Collection = "peers"
+)
+
+// metaLatencyConfigKey and metaNextEventIDKey are the CollectionMeta keys
+// Store's two scalar fields (as opposed to map/slice collections) persist
+// under.
+const (
+	metaLatencyConfigKey = "latency_config"
+	metaNextEventIDKey   = "next_event_id"
+)
+
+// Backend is the persistence seam Store mutates through. Implementations
+// trade off durability, write granularity, and deployment footprint:
+// JSONFileBackend dumps one file per collection (simple, no server to run,
+// but rewrites the whole collection on every Put); BoltBackend and
+// RedisBackend key each record individually, so a single AddSubscription
+// or SetNodeStatus call only touches the one record that changed.
+type Backend interface {
+	// Put writes value under (collection, key), creating or overwriting it.
+	Put(collection Collection, key string, value []byte) error
+	// Get reads the value at (collection, key). found is false if it's absent.
+	Get(collection Collection, key string) (value []byte, found bool, err error)
+	// Delete removes (collection, key). It is not an error if it's absent.
+	Delete(collection Collection, key string) error
+	// List returns every key/value pair currently in collection.
+	List(collection Collection) (map[string][]byte, error)
+	// Watch notifies on every Put/Delete to collection made through this
+	// process's own Backend instance. None of the implementations in this
+	// package see writes made by another instance sharing the same
+	// database or file (RedisBackend doesn't yet subscribe to Redis
+	// keyspace notifications) — internal/peering instead replicates
+	// across instances by forwarding each instance's own Watch stream to
+	// its peers over the network. Cancel stops the watch and closes the
+	// channel.
+	Watch(collection Collection) (events <-chan WatchEvent, cancel func())
+	// Close releases any resources (open file handles, DB connections)
+	// the backend holds.
+	Close() error
+}
+
+// WatchOp is the kind of change a WatchEvent reports.
+type WatchOp string
+
+const (
+	WatchPut    WatchOp = "put"
+	WatchDelete WatchOp = "delete"
+)
+
+// WatchEvent is one change reported by Backend.Watch.
+type WatchEvent struct {
+	Op    WatchOp
+	Key   string
+	Value []byte // empty for WatchDelete
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"bytes"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltBackend persists every collection in its own bucket of a single
+// bbolt file, so a Put/Delete only touches the one key that changed
+// instead of rewriting the whole collection like JSONFileBackend does.
+// It's the recommended backend for a single instance with more than a
+// handful of subscriptions/nodes.
+type BoltBackend struct {
+	db   *bbolt.DB
+	hubs map[Collection]*watchHub
+}
+
+// NewBoltBackend opens (creating if needed) a bbolt file at path.
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BoltBackend{db: db, hubs: make(map[Collection]*watchHub)}
+	for _, c := range []Collection{CollectionSubscriptions, CollectionNodeStates, CollectionWebhooks, CollectionDeliveries, CollectionMeta, CollectionPeers} {
+		b.hubs[c] = newWatchHub()
+		c := c
+		err := db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(c))
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *BoltBackend) Put(collection Collection, key string, value []byte) error {
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		return bucket.Put([]byte(key), bytes.Clone(value))
+	})
+	if err != nil {
+		return err
+	}
+	b.hubs[collection].notify(WatchEvent{Op: WatchPut, Key: key, Value: value})
+	return nil
+}
+
+func (b *BoltBackend) Get(collection Collection, key string) ([]byte, bool, error) {
+	var value []byte
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket([]byte(collection)).Get([]byte(key))
+		if raw != nil {
+			value = bytes.Clone(raw)
+		}
+		return nil
+	})
+	return value, value != nil, err
+}
+
+func (b *BoltBackend) Delete(collection Collection, key string) error {
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(collection)).Delete([]byte(key))
+	})
+	if err != nil {
+		return err
+	}
+	b.hubs[collection].notify(WatchEvent{Op: WatchDelete, Key: key})
+	return nil
+}
+
+func (b *BoltBackend) List(collection Collection) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(collection)).ForEach(func(k, v []byte) error {
+			result[string(k)] = bytes.Clone(v)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (b *BoltBackend) Watch(collection Collection) (<-chan WatchEvent, func()) {
+	return b.hubs[collection].subscribe()
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONFileBackend is the original, dependency-free persistence layer: one
+// JSON file per collection under dataDir, holding a map of key to
+// base64-encoded value bytes. It's the simplest backend to operate (no
+// server, no schema migration) but, unlike BoltBackend/RedisBackend, a
+// single Put rewrites the whole collection file, so it's best suited to
+// small deployments rather than ones churning through frequent
+// AddSubscription/SetNodeStatus calls.
+type JSONFileBackend struct {
+	mu      sync.Mutex
+	dataDir string
+	hubs    map[Collection]*watchHub
+	hubsMu  sync.Mutex
+}
+
+// NewJSONFileBackend creates a JSONFileBackend rooted at dataDir, creating
+// the directory if it doesn't exist. An empty dataDir makes every write a
+// no-op, matching the in-memory-only mode NewStore("") has always had.
+func NewJSONFileBackend(dataDir string) (*JSONFileBackend, error) {
+	if dataDir != "" {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &JSONFileBackend{dataDir: dataDir, hubs: make(map[Collection]*watchHub)}, nil
+}
+
+func (b *JSONFileBackend) path(collection Collection) string {
+	return filepath.Join(b.dataDir, string(collection)+".json")
+}
+
+func (b *JSONFileBackend) hub(collection Collection) *watchHub {
+	b.hubsMu.Lock()
+	defer b.hubsMu.Unlock()
+	h, ok := b.hubs[collection]
+	if !ok {
+		h = newWatchHub()
+		b.hubs[collection] = h
+	}
+	return h
+}
+
+func (b *JSONFileBackend) readAll(collection Collection) (map[string]string, error) {
+	if b.dataDir == "" {
+		return map[string]string{}, nil
+	}
+
+	raw, err := os.ReadFile(b.path(collection))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	var encoded map[string]string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+	if encoded == nil {
+		encoded = map[string]string{}
+	}
+	return encoded, nil
+}
+
+func (b *JSONFileBackend) writeAll(collection Collection, encoded map[string]string) error {
+	if b.dataDir == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(encoded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename, so a crash mid-write can't leave a
+	// truncated collection file behind.
+	tmp := b.path(collection) + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path(collection))
+}
+
+func (b *JSONFileBackend) Put(collection Collection, key string, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	encoded, err := b.readAll(collection)
+	if err != nil {
+		return err
+	}
+	encoded[key] = base64.StdEncoding.EncodeToString(value)
+	if err := b.writeAll(collection, encoded); err != nil {
+		return err
+	}
+
+	b.hub(collection).notify(WatchEvent{Op: WatchPut, Key: key, Value: value})
+	return nil
+}
+
+func (b *JSONFileBackend) Get(collection Collection, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	encoded, err := b.readAll(collection)
+	if err != nil {
+		return nil, false, err
+	}
+	raw, ok := encoded[key]
+	if !ok {
+		return nil, false, nil
+	}
+	value, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (b *JSONFileBackend) Delete(collection Collection, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	encoded, err := b.readAll(collection)
+	if err != nil {
+		return err
+	}
+	if _, ok := encoded[key]; !ok {
+		return nil
+	}
+	delete(encoded, key)
+	if err := b.writeAll(collection, encoded); err != nil {
+		return err
+	}
+
+	b.hub(collection).notify(WatchEvent{Op: WatchDelete, Key: key})
+	return nil
+}
+
+func (b *JSONFileBackend) List(collection Collection) (map[string][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	encoded, err := b.readAll(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(encoded))
+	for key, raw := range encoded {
+		value, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			continue
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func (b *JSONFileBackend) Watch(collection Collection) (<-chan WatchEvent, func()) {
+	return b.hub(collection).subscribe()
+}
+
+func (b *JSONFileBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces this package's hashes so a shared Redis
+// instance can host other applications' keys alongside it.
+const redisKeyPrefix = "easy_proxies:"
+
+// RedisBackend persists each collection as a single Redis hash (HSET
+// collection key value), one field per record. Unlike BoltBackend, its
+// writes are visible to every process pointed at the same Redis instance,
+// which is what makes it the natural backend for multi-instance
+// deployments that want to share subscriptions and node health (see
+// internal/peering) instead of each instance keeping its own file.
+type RedisBackend struct {
+	client *redis.Client
+	hubs   map[Collection]*watchHub
+}
+
+// NewRedisBackend connects to a Redis instance at addr (host:port).
+func NewRedisBackend(addr string) (*RedisBackend, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+
+	hubs := make(map[Collection]*watchHub)
+	for _, c := range []Collection{CollectionSubscriptions, CollectionNodeStates, CollectionWebhooks, CollectionDeliveries, CollectionMeta, CollectionPeers} {
+		hubs[c] = newWatchHub()
+	}
+	return &RedisBackend{client: client, hubs: hubs}, nil
+}
+
+func (b *RedisBackend) hashKey(collection Collection) string {
+	return redisKeyPrefix + string(collection)
+}
+
+func (b *RedisBackend) Put(collection Collection, key string, value []byte) error {
+	if err := b.client.HSet(context.Background(), b.hashKey(collection), key, value).Err(); err != nil {
+		return err
+	}
+	b.hubs[collection].notify(WatchEvent{Op: WatchPut, Key: key, Value: value})
+	return nil
+}
+
+func (b *RedisBackend) Get(collection Collection, key string) ([]byte, bool, error) {
+	value, err := b.client.HGet(context.Background(), b.hashKey(collection), key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (b *RedisBackend) Delete(collection Collection, key string) error {
+	if err := b.client.HDel(context.Background(), b.hashKey(collection), key).Err(); err != nil {
+		return err
+	}
+	b.hubs[collection].notify(WatchEvent{Op: WatchDelete, Key: key})
+	return nil
+}
+
+func (b *RedisBackend) List(collection Collection) (map[string][]byte, error) {
+	all, err := b.client.HGetAll(context.Background(), b.hashKey(collection)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(all))
+	for key, value := range all {
+		result[key] = []byte(value)
+	}
+	return result, nil
+}
+
+// Watch fans out this process's own writes immediately, the same as
+// JSONFileBackend/BoltBackend. It does not subscribe to Redis keyspace
+// notifications, so it can't see another instance's writes; peering
+// (internal/peering) instead forwards each instance's own store.Watch
+// stream to its peers.
+func (b *RedisBackend) Watch(collection Collection) (<-chan WatchEvent, func()) {
+	return b.hubs[collection].subscribe()
+}
+
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,48 @@
+package store
+
+import "sync"
+
+// watchHub fans WatchEvents for one collection out to subscribers,
+// non-blocking the same way Store.publish is: a subscriber too slow to
+// drain its channel misses events rather than stalling the writer. Every
+// in-process Backend embeds one per collection it supports Watch on.
+type watchHub struct {
+	mu        sync.Mutex
+	nextID    int
+	observers map[int]chan WatchEvent
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{observers: make(map[int]chan WatchEvent)}
+}
+
+func (h *watchHub) subscribe() (<-chan WatchEvent, func()) {
+	ch := make(chan WatchEvent, 16)
+
+	h.mu.Lock()
+	h.nextID++
+	id := h.nextID
+	h.observers[id] = ch
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.observers[id]; ok {
+			delete(h.observers, id)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+func (h *watchHub) notify(evt WatchEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.observers {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
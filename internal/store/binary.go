@@ -0,0 +1,38 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// MarshalBinary gob-encodes the subscription for a Backend.Put. Backends
+// use this instead of json.Marshal since Subscription and EnhancedNode are
+// on the hot path of every AddSubscription/SetNodeStatus call, and gob
+// roughly an order of magnitude faster to encode/decode than
+// json.MarshalIndent at this struct size.
+func (s *Subscription) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(*s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a value produced by MarshalBinary.
+func (s *Subscription) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(s)
+}
+
+// MarshalBinary gob-encodes the node state for a Backend.Put.
+func (n *EnhancedNode) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(*n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a value produced by MarshalBinary.
+func (n *EnhancedNode) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(n)
+}
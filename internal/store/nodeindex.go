@@ -0,0 +1,193 @@
+package store
+
+import "strconv"
+
+// nodeIndex maintains memdb-style secondary indexes over nodeStates, so
+// List*/GetGroupedBy* are a bucket lookup instead of a full scan of every
+// node. Each index is bucket key -> primary key (the same key nodeStates
+// itself uses, node.Name or node.URI) -> *EnhancedNode.
+type nodeIndex struct {
+	byRegion        map[string]map[string]*EnhancedNode
+	byLatency       map[string]map[string]*EnhancedNode
+	bySubscription  map[string]map[string]*EnhancedNode
+	byStatusAvail   map[string]map[string]*EnhancedNode // statusAvailKey(status, available)
+	byRegionLatency map[string]map[string]*EnhancedNode // regionLatencyKey(region, level)
+
+	// keys records the bucket key each primary key currently sits under in
+	// every index above. put reads this to find and evict the stale entry
+	// before re-indexing, which matters because callers (resyncService,
+	// most notably) fetch a node via GetNodeState, mutate its fields in
+	// place, and only then call put/UpdateNodeState with that same
+	// pointer — by the time put runs, the "old" values are already gone
+	// from the struct itself, so they have to come from here instead.
+	keys map[string]indexKeys
+}
+
+type indexKeys struct {
+	region, latency, subscription, statusAvail, regionLatency string
+}
+
+func newNodeIndex() *nodeIndex {
+	return &nodeIndex{
+		byRegion:        make(map[string]map[string]*EnhancedNode),
+		byLatency:       make(map[string]map[string]*EnhancedNode),
+		bySubscription:  make(map[string]map[string]*EnhancedNode),
+		byStatusAvail:   make(map[string]map[string]*EnhancedNode),
+		byRegionLatency: make(map[string]map[string]*EnhancedNode),
+		keys:            make(map[string]indexKeys),
+	}
+}
+
+func statusAvailKey(status NodeStatus, available bool) string {
+	return string(status) + "|" + strconv.FormatBool(available)
+}
+
+func regionLatencyKey(region string, level LatencyLevel) string {
+	return region + "|" + string(level)
+}
+
+func bucketAdd(buckets map[string]map[string]*EnhancedNode, bucket, key string, node *EnhancedNode) {
+	b, ok := buckets[bucket]
+	if !ok {
+		b = make(map[string]*EnhancedNode)
+		buckets[bucket] = b
+	}
+	b[key] = node
+}
+
+func bucketRemove(buckets map[string]map[string]*EnhancedNode, bucket, key string) {
+	b, ok := buckets[bucket]
+	if !ok {
+		return
+	}
+	delete(b, key)
+	if len(b) == 0 {
+		delete(buckets, bucket)
+	}
+}
+
+// put (re)indexes node under key, evicting whatever bucket entries it
+// previously held first. Safe to call whether key is new or already
+// indexed.
+func (idx *nodeIndex) put(key string, node *EnhancedNode) {
+	if old, ok := idx.keys[key]; ok {
+		bucketRemove(idx.byRegion, old.region, key)
+		bucketRemove(idx.byLatency, old.latency, key)
+		bucketRemove(idx.bySubscription, old.subscription, key)
+		bucketRemove(idx.byStatusAvail, old.statusAvail, key)
+		bucketRemove(idx.byRegionLatency, old.regionLatency, key)
+	}
+
+	nk := indexKeys{
+		region:        node.Region,
+		latency:       string(node.LatencyLevel),
+		subscription:  node.SubscriptionID,
+		statusAvail:   statusAvailKey(node.Status, node.Available),
+		regionLatency: regionLatencyKey(node.Region, node.LatencyLevel),
+	}
+	bucketAdd(idx.byRegion, nk.region, key, node)
+	bucketAdd(idx.byLatency, nk.latency, key, node)
+	bucketAdd(idx.bySubscription, nk.subscription, key, node)
+	bucketAdd(idx.byStatusAvail, nk.statusAvail, key, node)
+	bucketAdd(idx.byRegionLatency, nk.regionLatency, key, node)
+	idx.keys[key] = nk
+}
+
+// remove evicts key from every index bucket it's currently in.
+func (idx *nodeIndex) remove(key string) {
+	old, ok := idx.keys[key]
+	if !ok {
+		return
+	}
+	bucketRemove(idx.byRegion, old.region, key)
+	bucketRemove(idx.byLatency, old.latency, key)
+	bucketRemove(idx.bySubscription, old.subscription, key)
+	bucketRemove(idx.byStatusAvail, old.statusAvail, key)
+	bucketRemove(idx.byRegionLatency, old.regionLatency, key)
+	delete(idx.keys, key)
+}
+
+// NodeFilter narrows Store.Query to nodes matching every non-zero field.
+// An unset Available leaves availability unfiltered; to require a
+// specific value set AvailableSet and Available.
+type NodeFilter struct {
+	Region         string
+	LatencyLevel   LatencyLevel
+	SubscriptionID string
+	Status         NodeStatus
+	AvailableSet   bool
+	Available      bool
+}
+
+// snapshot copies a bucket's values out into a slice, the same shape
+// every List* method has always returned.
+func snapshot(bucket map[string]*EnhancedNode) []*EnhancedNode {
+	result := make([]*EnhancedNode, 0, len(bucket))
+	for _, node := range bucket {
+		result = append(result, node)
+	}
+	return result
+}
+
+// query picks the most selective index available for filter and returns
+// the matching nodes, filtering out any bucket-matched node that doesn't
+// also satisfy the filter fields the chosen index didn't cover. Callers
+// hold s.mu for reading; query itself takes no lock.
+func (idx *nodeIndex) query(filter NodeFilter) []*EnhancedNode {
+	var candidates map[string]*EnhancedNode
+
+	switch {
+	case filter.Region != "" && filter.LatencyLevel != "":
+		candidates = idx.byRegionLatency[regionLatencyKey(filter.Region, filter.LatencyLevel)]
+	case filter.Status != "" && filter.AvailableSet:
+		candidates = idx.byStatusAvail[statusAvailKey(filter.Status, filter.Available)]
+	case filter.SubscriptionID != "":
+		candidates = idx.bySubscription[filter.SubscriptionID]
+	case filter.Region != "":
+		candidates = idx.byRegion[filter.Region]
+	case filter.LatencyLevel != "":
+		candidates = idx.byLatency[string(filter.LatencyLevel)]
+	default:
+		// No index covers filter: start from every node (the region index
+		// always has full coverage) and let the loop below filter it.
+		candidates = make(map[string]*EnhancedNode)
+		for _, bucket := range idx.byRegion {
+			for key, node := range bucket {
+				candidates[key] = node
+			}
+		}
+	}
+
+	if candidates == nil && filter == (NodeFilter{}) {
+		// Genuinely unfiltered: union every node via the region index,
+		// which always has full coverage.
+		result := make([]*EnhancedNode, 0)
+		for _, bucket := range idx.byRegion {
+			for _, node := range bucket {
+				result = append(result, node)
+			}
+		}
+		return result
+	}
+
+	result := make([]*EnhancedNode, 0, len(candidates))
+	for _, node := range candidates {
+		if filter.Region != "" && node.Region != filter.Region {
+			continue
+		}
+		if filter.LatencyLevel != "" && node.LatencyLevel != filter.LatencyLevel {
+			continue
+		}
+		if filter.SubscriptionID != "" && node.SubscriptionID != filter.SubscriptionID {
+			continue
+		}
+		if filter.Status != "" && node.Status != filter.Status {
+			continue
+		}
+		if filter.AvailableSet && node.Available != filter.Available {
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Peer is another easy_proxies instance this one replicates subscriptions
+// and node health with, following Consul's cluster peering model. Address
+// is the peer's peering endpoint (see internal/peering); Token is a shared
+// bearer secret both directions of the stream authenticate with.
+type Peer struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Address    string        `json:"address"`
+	Token      string        `json:"token"`
+	Connected  bool          `json:"connected"`
+	LastSyncAt time.Time     `json:"last_sync_at,omitempty"`
+	Lag        time.Duration `json:"lag,omitempty"`
+	LastError  string        `json:"last_error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// AddPeer registers a new peer instance to replicate with. The returned
+// Peer's ID is what internal/peering.Manager and UpdatePeerStatus identify
+// it by afterward.
+func (s *Store) AddPeer(name, address, token string) (*Peer, error) {
+	s.mu.Lock()
+	peer := &Peer{
+		ID:        generateID(),
+		Name:      name,
+		Address:   address,
+		Token:     token,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	s.peers[peer.ID] = peer
+	err := s.putPeer(peer)
+	s.mu.Unlock()
+	return peer, err
+}
+
+// GetPeer retrieves a peer by ID.
+func (s *Store) GetPeer(id string) (*Peer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peer, exists := s.peers[id]
+	if !exists {
+		return nil, ErrPeerNotFound
+	}
+	return peer, nil
+}
+
+// ListPeers returns every registered peer.
+func (s *Store) ListPeers() []*Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Peer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		result = append(result, peer)
+	}
+	return result
+}
+
+// DeletePeer removes a peer registration.
+func (s *Store) DeletePeer(id string) error {
+	s.mu.Lock()
+	if _, exists := s.peers[id]; !exists {
+		s.mu.Unlock()
+		return ErrPeerNotFound
+	}
+	delete(s.peers, id)
+	err := s.backend.Delete(CollectionPeers, id)
+	s.mu.Unlock()
+	return err
+}
+
+// UpdatePeerStatus records a peer's latest connection/sync state, mirroring
+// how Subscription exposes LastRefreshAt/LastError. internal/peering.Manager
+// calls this after every reconnect attempt and sync round.
+func (s *Store) UpdatePeerStatus(id string, connected bool, lastSyncAt time.Time, lag time.Duration, lastErr string) error {
+	s.mu.Lock()
+	peer, exists := s.peers[id]
+	if !exists {
+		s.mu.Unlock()
+		return ErrPeerNotFound
+	}
+	peer.Connected = connected
+	if !lastSyncAt.IsZero() {
+		peer.LastSyncAt = lastSyncAt
+	}
+	peer.Lag = lag
+	peer.LastError = lastErr
+	peer.UpdatedAt = time.Now()
+	err := s.putPeer(peer)
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Store) putPeer(peer *Peer) error {
+	value, err := json.Marshal(peer)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(CollectionPeers, peer.ID, value)
+}
@@ -1,25 +1,38 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 )
 
+// SubscriptionOrigin identifies where a Subscription was added from: typed
+// in by a user/the config file, or discovered from a live etcd/Consul KV
+// prefix. Only SubscriptionOriginStatic subscriptions may be deleted through the API;
+// discovered ones are removed by their source when the backing key
+// disappears.
+type SubscriptionOrigin string
+
+const (
+	SubscriptionOriginStatic SubscriptionOrigin = "static"
+	SubscriptionOriginEtcd   SubscriptionOrigin = "etcd"
+	SubscriptionOriginConsul SubscriptionOrigin = "consul"
+)
+
 // Subscription represents a proxy subscription source
 type Subscription struct {
-	ID              string    `json:"id" yaml:"id"`
-	Name            string    `json:"name" yaml:"name"`
-	URL             string    `json:"url" yaml:"url"`
-	Enabled         bool      `json:"enabled" yaml:"enabled"`
-	RefreshInterval string    `json:"refresh_interval" yaml:"refresh_interval"` // e.g., "1h", "30m"
-	LastRefreshAt   time.Time `json:"last_refresh_at" yaml:"-"`
-	NodeCount       int       `json:"node_count" yaml:"-"`
-	LastError       string    `json:"last_error,omitempty" yaml:"-"`
-	CreatedAt       time.Time `json:"created_at" yaml:"-"`
-	UpdatedAt       time.Time `json:"updated_at" yaml:"-"`
+	ID              string             `json:"id" yaml:"id"`
+	Name            string             `json:"name" yaml:"name"`
+	URL             string             `json:"url" yaml:"url"`
+	Enabled         bool               `json:"enabled" yaml:"enabled"`
+	RefreshInterval string             `json:"refresh_interval" yaml:"refresh_interval"` // e.g., "1h", "30m"
+	Origin          SubscriptionOrigin `json:"origin,omitempty" yaml:"-"`
+	LastRefreshAt   time.Time          `json:"last_refresh_at" yaml:"-"`
+	NodeCount       int                `json:"node_count" yaml:"-"`
+	LastError       string             `json:"last_error,omitempty" yaml:"-"`
+	CreatedAt       time.Time          `json:"created_at" yaml:"-"`
+	UpdatedAt       time.Time          `json:"updated_at" yaml:"-"`
 }
 
 // NodeStatus represents the status of a node
@@ -53,10 +66,12 @@ type EnhancedNode struct {
 	Latency          int64        `json:"latency"`               // in milliseconds, -1 if unknown
 	LatencyLevel     LatencyLevel `json:"latency_level"`
 	Status           NodeStatus   `json:"status"`
+	StatusReason     string       `json:"status_reason,omitempty"` // why Status is what it is, e.g. a permanent auth failure
 	Available        bool         `json:"available"`
 	LastCheckAt      time.Time    `json:"last_check_at,omitempty"`
 	FailureCount     int          `json:"failure_count"`
 	SuccessCount     int64        `json:"success_count"`
+	Version          uint64       `json:"version"` // bumped on every GuaranteedUpdateNode commit
 }
 
 // LatencyConfig defines thresholds for latency grouping
@@ -73,38 +88,137 @@ const (
 	PoolModeRandom       PoolMode = "random"
 	PoolModeLatencyFirst PoolMode = "latency_first"
 	PoolModeWeighted     PoolMode = "weighted"
+	PoolModeP2C          PoolMode = "p2c" // power-of-two-choices, see proxypool.ProxyPool.selectP2C
 )
 
-// Store handles persistent storage of subscriptions and node states
+// Webhook is a registered endpoint notified on lifecycle events (node
+// up/down/blacklist, subscription add/delete, reload finished). Events is
+// the subscriber's event mask; an empty mask receives everything.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one pending or retrying webhook POST, persisted so a
+// restart doesn't drop events that were queued but not yet delivered.
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	EventID       int64           `json:"event_id"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+// maxQueuedDeliveries bounds the persisted retry queue; once full, the
+// oldest pending delivery is dropped to make room rather than growing
+// without bound if a webhook endpoint stays down indefinitely.
+const maxQueuedDeliveries = 500
+
+// EventType categorizes a published Event.
+type EventType string
+
+const (
+	EventNodeUpdated         EventType = "node_updated"
+	EventNodeRemoved         EventType = "node_removed"
+	EventSubscriptionUpdated EventType = "subscription_updated"
+	EventSubscriptionRemoved EventType = "subscription_removed"
+)
+
+// Event describes a single state transition published on the store's event
+// bus: node status/latency changes, subscription refreshes, and the like.
+// Subscribers (e.g. the SSE endpoint) use this instead of polling the API.
+type Event struct {
+	Type         EventType     `json:"type"`
+	Node         *EnhancedNode `json:"node,omitempty"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	At           time.Time     `json:"at"`
+}
+
+// Store handles storage of subscriptions and node states, delegating the
+// actual persistence to a pluggable Backend (see backend.go).
 type Store struct {
 	mu            sync.RWMutex
-	dataDir       string
+	backend       Backend
 	subscriptions map[string]*Subscription
 	nodeStates    map[string]*EnhancedNode // key: node name or URI hash
+	nodeIdx       *nodeIndex
 	latencyConfig LatencyConfig
+
+	webhooks      map[string]*Webhook
+	deliveryQueue []*WebhookDelivery
+	nextEventID   int64
+	peers         map[string]*Peer
+
+	subMu       sync.RWMutex
+	nextSubID   int
+	subscribers map[int]chan Event
+
+	nodeReloader NodeReloader
+}
+
+// NodeReloader triggers boxmgr's node reload, the same interface
+// internal/subscription's Manager debounces TriggerReload calls through.
+// ApplyTxn uses it to regenerate boxmgr's config once after a batch that
+// added or removed nodes, instead of leaving boxmgr's live config to drift
+// from the store until the next periodic resync.
+type NodeReloader interface {
+	TriggerReload(ctx context.Context) error
 }
 
-// NewStore creates a new store instance
+// txnReloadTimeout bounds the TriggerReload call ApplyTxn makes after a
+// successful batch, mirroring internal/subscription's reloadTimeout.
+const txnReloadTimeout = 30 * time.Second
+
+// SetNodeReloader wires boxmgr into ApplyTxn so add-node/remove-node ops
+// regenerate its config. Safe to leave unset in tests or other contexts
+// that construct a Store without a boxmgr.Manager; ApplyTxn just skips
+// the reload in that case.
+func (s *Store) SetNodeReloader(r NodeReloader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodeReloader = r
+}
+
+// NewStore creates a Store backed by a JSONFileBackend rooted at dataDir,
+// the dependency-free default this package has always shipped. Pass ""
+// for an in-memory-only store (nothing is read or written to disk). Use
+// NewStoreWithBackend directly to plug in BoltBackend or RedisBackend
+// instead.
 func NewStore(dataDir string) (*Store, error) {
+	backend, err := NewJSONFileBackend(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithBackend(backend)
+}
+
+// NewStoreWithBackend creates a Store persisted through backend, loading
+// whatever state it already holds.
+func NewStoreWithBackend(backend Backend) (*Store, error) {
 	s := &Store{
-		dataDir:       dataDir,
+		backend:       backend,
 		subscriptions: make(map[string]*Subscription),
 		nodeStates:    make(map[string]*EnhancedNode),
+		nodeIdx:       newNodeIndex(),
 		latencyConfig: LatencyConfig{
 			LowThreshold:    100,
 			MediumThreshold: 300,
 		},
+		webhooks:    make(map[string]*Webhook),
+		peers:       make(map[string]*Peer),
+		subscribers: make(map[int]chan Event),
 	}
 
-	// Create data directory if not exists
-	if dataDir != "" {
-		if err := os.MkdirAll(dataDir, 0755); err != nil {
-			return nil, err
-		}
-		// Load existing data
-		_ = s.load()
+	if err := s.load(); err != nil {
+		return nil, err
 	}
-
 	return s, nil
 }
 
@@ -145,7 +259,6 @@ func (s *Store) CalculateLatencyLevel(latencyMs int64) LatencyLevel {
 // AddSubscription adds a new subscription
 func (s *Store) AddSubscription(sub *Subscription) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	if sub.ID == "" {
 		sub.ID = generateID()
@@ -155,33 +268,66 @@ func (s *Store) AddSubscription(sub *Subscription) error {
 	if sub.RefreshInterval == "" {
 		sub.RefreshInterval = "1h"
 	}
+	if sub.Origin == "" {
+		sub.Origin = SubscriptionOriginStatic
+	}
 	s.subscriptions[sub.ID] = sub
-	return s.save()
+	err := s.putSubscription(sub)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventSubscriptionUpdated, Subscription: sub})
+	return err
 }
 
 // UpdateSubscription updates an existing subscription
 func (s *Store) UpdateSubscription(sub *Subscription) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if _, exists := s.subscriptions[sub.ID]; !exists {
+		s.mu.Unlock()
 		return ErrSubscriptionNotFound
 	}
 	sub.UpdatedAt = time.Now()
 	s.subscriptions[sub.ID] = sub
-	return s.save()
+	err := s.putSubscription(sub)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventSubscriptionUpdated, Subscription: sub})
+	return err
+}
+
+// ApplySubscriptionUpdate installs an externally-sourced subscription
+// record (see internal/peering) exactly as given, including its
+// UpdatedAt, instead of stamping a fresh one the way
+// AddSubscription/UpdateSubscription do for locally-initiated changes.
+// Callers are expected to have already resolved last-writer-wins before
+// calling this, so it always applies and publishes.
+func (s *Store) ApplySubscriptionUpdate(sub *Subscription) error {
+	s.mu.Lock()
+	if _, exists := s.subscriptions[sub.ID]; !exists && sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now()
+	}
+	s.subscriptions[sub.ID] = sub
+	err := s.putSubscription(sub)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventSubscriptionUpdated, Subscription: sub})
+	return err
 }
 
 // DeleteSubscription removes a subscription
 func (s *Store) DeleteSubscription(id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if _, exists := s.subscriptions[id]; !exists {
+	sub, exists := s.subscriptions[id]
+	if !exists {
+		s.mu.Unlock()
 		return ErrSubscriptionNotFound
 	}
 	delete(s.subscriptions, id)
-	return s.save()
+	err := s.backend.Delete(CollectionSubscriptions, id)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventSubscriptionRemoved, Subscription: sub})
+	return err
 }
 
 // GetSubscription retrieves a subscription by ID
@@ -208,18 +354,371 @@ func (s *Store) ListSubscriptions() []*Subscription {
 	return result
 }
 
+// --- Webhooks ---
+
+// AddWebhook registers a new webhook endpoint.
+func (s *Store) AddWebhook(wh *Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if wh.ID == "" {
+		wh.ID = generateID()
+	}
+	wh.CreatedAt = time.Now()
+	wh.UpdatedAt = time.Now()
+	s.webhooks[wh.ID] = wh
+	return s.putWebhook(wh)
+}
+
+// GetWebhook retrieves a webhook by ID.
+func (s *Store) GetWebhook(id string) (*Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wh, exists := s.webhooks[id]
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+	return wh, nil
+}
+
+// ListWebhooks returns every registered webhook.
+func (s *Store) ListWebhooks() []*Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Webhook, 0, len(s.webhooks))
+	for _, wh := range s.webhooks {
+		result = append(result, wh)
+	}
+	return result
+}
+
+// DeleteWebhook removes a webhook registration.
+func (s *Store) DeleteWebhook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	return s.backend.Delete(CollectionWebhooks, id)
+}
+
+// NextEventID returns a monotonically increasing ID for webhook payloads,
+// so subscribers can detect gaps or duplicate deliveries.
+func (s *Store) NextEventID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextEventID++
+	_ = s.putNextEventID()
+	return s.nextEventID
+}
+
+// EnqueueDelivery persists a pending webhook delivery so it survives a
+// restart. If the queue is already at maxQueuedDeliveries, the oldest
+// pending delivery is dropped to make room.
+func (s *Store) EnqueueDelivery(d *WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.deliveryQueue) >= maxQueuedDeliveries {
+		s.deliveryQueue = s.deliveryQueue[1:]
+	}
+	s.deliveryQueue = append(s.deliveryQueue, d)
+	return s.putDeliveryQueue()
+}
+
+// ListPendingDeliveries returns every queued webhook delivery, e.g. so a
+// dispatcher can re-enqueue them into its worker pool after a restart.
+func (s *Store) ListPendingDeliveries() []*WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*WebhookDelivery, len(s.deliveryQueue))
+	copy(result, s.deliveryQueue)
+	return result
+}
+
+// UpdateDelivery persists a delivery's updated attempt count/backoff after
+// a failed send.
+func (s *Store) UpdateDelivery(d *WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.deliveryQueue {
+		if existing.ID == d.ID {
+			s.deliveryQueue[i] = d
+			return s.putDeliveryQueue()
+		}
+	}
+	return nil
+}
+
+// RemoveDelivery removes a delivery from the queue, e.g. once it succeeds
+// or exhausts its retries.
+func (s *Store) RemoveDelivery(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.deliveryQueue {
+		if existing.ID == id {
+			s.deliveryQueue = append(s.deliveryQueue[:i], s.deliveryQueue[i+1:]...)
+			_ = s.putDeliveryQueue()
+			return
+		}
+	}
+}
+
+// --- Transactions ---
+
+// TxnOp is a single operation in a Store.ApplyTxn batch.
+type TxnOp struct {
+	Op             string        `json:"op"`                        // add-node, remove-node, enable-subscription, get-or-empty-node
+	Name           string        `json:"name,omitempty"`            // node name, for remove-node / get-or-empty-node
+	Node           *EnhancedNode `json:"node,omitempty"`            // for add-node
+	SubscriptionID string        `json:"subscription_id,omitempty"` // for enable-subscription
+}
+
+// TxnResult is the outcome of one TxnOp within an ApplyTxn batch.
+type TxnResult struct {
+	Op    string `json:"op"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// ApplyTxn applies a batch of node/subscription mutations atomically: ops
+// are validated against a single consistent snapshot before anything is
+// written, so a later op's failure (e.g. enable-subscription naming an ID
+// that doesn't exist) aborts the whole batch rather than leaving the
+// earlier ops' writes in place with no way to undo them. get-or-empty-node
+// never fails validation; it returns an empty EnhancedNode for a missing
+// key, so callers can build idempotent reconciliation scripts without
+// handling 404s. A successful batch that added or removed a node triggers
+// one TriggerReload call on the SetNodeReloader-installed reloader so
+// boxmgr's config reflects it.
+func (s *Store) ApplyTxn(ops []TxnOp) []TxnResult {
+	results := make([]TxnResult, len(ops))
+
+	s.mu.Lock()
+
+	for i, op := range ops {
+		switch op.Op {
+		case "add-node":
+			if op.Node == nil {
+				results[i] = TxnResult{Op: op.Op, Error: "node is required"}
+			}
+		case "enable-subscription":
+			if _, ok := s.subscriptions[op.SubscriptionID]; !ok {
+				results[i] = TxnResult{Op: op.Op, Error: ErrSubscriptionNotFound.Error()}
+			}
+		case "remove-node", "get-or-empty-node":
+			// Always valid: removing/reading a missing key is a no-op, not an error.
+		default:
+			results[i] = TxnResult{Op: op.Op, Error: "unknown op: " + op.Op}
+		}
+	}
+
+	for _, res := range results {
+		if res.Error != "" {
+			s.mu.Unlock()
+			for i := range results {
+				if results[i] == (TxnResult{}) {
+					results[i] = TxnResult{Op: ops[i].Op, Error: "aborted: another op in batch failed validation"}
+				}
+			}
+			return results
+		}
+	}
+
+	var events []Event
+	nodesChanged := false
+
+	for i, op := range ops {
+		switch op.Op {
+		case "add-node":
+			key := op.Node.Name
+			if key == "" {
+				key = op.Node.URI
+			}
+			s.nodeStates[key] = op.Node
+			s.nodeIdx.put(key, op.Node)
+			_ = s.putNode(key, op.Node)
+			results[i] = TxnResult{Op: op.Op, OK: true}
+			events = append(events, Event{Type: EventNodeUpdated, Node: op.Node})
+			nodesChanged = true
+
+		case "remove-node":
+			node, existed := s.nodeStates[op.Name]
+			delete(s.nodeStates, op.Name)
+			if existed {
+				s.nodeIdx.remove(op.Name)
+				_ = s.backend.Delete(CollectionNodeStates, op.Name)
+				events = append(events, Event{Type: EventNodeRemoved, Node: node})
+				nodesChanged = true
+			}
+			results[i] = TxnResult{Op: op.Op, OK: true}
+
+		case "enable-subscription":
+			sub := s.subscriptions[op.SubscriptionID]
+			sub.Enabled = true
+			sub.UpdatedAt = time.Now()
+			_ = s.putSubscription(sub)
+			results[i] = TxnResult{Op: op.Op, OK: true}
+			events = append(events, Event{Type: EventSubscriptionUpdated, Subscription: sub})
+
+		case "get-or-empty-node":
+			node, ok := s.nodeStates[op.Name]
+			if !ok {
+				node = &EnhancedNode{}
+			}
+			results[i] = TxnResult{Op: op.Op, OK: true, Data: node}
+		}
+	}
+
+	reloader := s.nodeReloader
+	s.mu.Unlock()
+
+	for _, evt := range events {
+		s.publish(evt)
+	}
+	if nodesChanged && reloader != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), txnReloadTimeout)
+			defer cancel()
+			_ = reloader.TriggerReload(ctx)
+		}()
+	}
+	return results
+}
+
+// --- Event Bus ---
+
+// Subscribe returns a channel of Events published by UpdateNodeState,
+// RemoveNodeState, SetNodeStatus/DisableNode, and
+// AddSubscription/UpdateSubscription/DeleteSubscription. Callers must pass
+// the returned channel to Unsubscribe when done, or use Watch instead for
+// a channel that's cleaned up automatically when ctx is canceled.
+func (s *Store) Subscribe() <-chan Event {
+	ch := make(chan Event, 16)
+
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	s.nextSubID++
+	s.subscribers[s.nextSubID] = ch
+	return ch
+}
+
+// Unsubscribe removes a channel previously returned by Subscribe and closes
+// it. It's a no-op if the channel was already unsubscribed.
+func (s *Store) Unsubscribe(ch <-chan Event) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for id, c := range s.subscribers {
+		if c == ch {
+			delete(s.subscribers, id)
+			close(c)
+			return
+		}
+	}
+}
+
+// publish broadcasts an event to every subscriber. Sends are non-blocking:
+// a subscriber too slow to drain its channel misses events rather than
+// stalling the publisher.
+func (s *Store) publish(evt Event) {
+	evt.At = time.Now()
+
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// EventFilter narrows Watch to events matching every non-zero field. A
+// zero-valued EventFilter matches everything.
+type EventFilter struct {
+	Types          []EventType
+	SubscriptionID string
+}
+
+// matches reports whether evt passes every field filter sets.
+func (f EventFilter) matches(evt Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if evt.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.SubscriptionID != "" {
+		if evt.Subscription == nil || evt.Subscription.ID != f.SubscriptionID {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch is a convenience layer over Subscribe/Unsubscribe modeled on
+// Consul's state store watchsets: it returns a channel of events matching
+// filter and tears itself down (unsubscribing and closing the returned
+// channel) when ctx is canceled, so callers don't need their own shutdown
+// path. The returned channel shares Subscribe's non-blocking semantics: an
+// event arriving while it is full is dropped.
+func (s *Store) Watch(ctx context.Context, filter EventFilter) <-chan Event {
+	src := s.Subscribe()
+	out := make(chan Event, 16)
+
+	go func() {
+		defer close(out)
+		defer s.Unsubscribe(src)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-src:
+				if !ok {
+					return
+				}
+				if !filter.matches(evt) {
+					continue
+				}
+				select {
+				case out <- evt:
+				default:
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
 // --- Node State Methods ---
 
 // UpdateNodeState updates or creates a node state
 func (s *Store) UpdateNodeState(node *EnhancedNode) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	key := node.Name
 	if key == "" {
 		key = node.URI
 	}
 	s.nodeStates[key] = node
+	s.nodeIdx.put(key, node)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventNodeUpdated, Node: node})
 }
 
 // GetNodeState retrieves a node state
@@ -231,167 +730,327 @@ func (s *Store) GetNodeState(name string) (*EnhancedNode, bool) {
 	return node, exists
 }
 
-// SetNodeStatus updates the status of a node
+// SetNodeStatus updates the status of a node. It goes through
+// GuaranteedUpdateNode so a status change racing with a latency probe's
+// UpdateNodeState can't silently lose one or the other.
 func (s *Store) SetNodeStatus(name string, status NodeStatus) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.GuaranteedUpdateNode(name, func(current *EnhancedNode) (*EnhancedNode, error) {
+		current.Status = status
+		current.StatusReason = ""
+		return current, nil
+	})
+}
+
+// GuaranteedUpdateNode applies tryUpdate to name's current state using
+// optimistic concurrency, borrowing the GuaranteedUpdate pattern from
+// Kubernetes' etcd storage: it reads the current node under RLock, invokes
+// tryUpdate on a deep copy, then takes the write lock and commits only if
+// no other writer has bumped Version in the meantime; if one has, it
+// re-reads and retries tryUpdate against the newer value. tryUpdate can
+// return ErrConflict itself to abort instead of being retried.
+func (s *Store) GuaranteedUpdateNode(name string, tryUpdate func(current *EnhancedNode) (*EnhancedNode, error)) error {
+	for {
+		s.mu.RLock()
+		current, exists := s.nodeStates[name]
+		s.mu.RUnlock()
+		if !exists {
+			return ErrNodeNotFound
+		}
+
+		working := *current
+		updated, err := tryUpdate(&working)
+		if err != nil {
+			return err
+		}
 
+		s.mu.Lock()
+		latest, exists := s.nodeStates[name]
+		if !exists {
+			s.mu.Unlock()
+			return ErrNodeNotFound
+		}
+		if latest.Version != current.Version {
+			s.mu.Unlock()
+			continue
+		}
+		updated.Version = latest.Version + 1
+		s.nodeStates[name] = updated
+		s.nodeIdx.put(name, updated)
+		err = s.putNode(name, updated)
+		s.mu.Unlock()
+
+		s.publish(Event{Type: EventNodeUpdated, Node: updated})
+		return err
+	}
+}
+
+// DisableNode forces a node into NodeStatusDisabled, recording why. It's
+// used to surface irrecoverable errors (e.g. a permanent auth failure
+// reported by boxmgr) so bad state doesn't just persist indefinitely.
+func (s *Store) DisableNode(name, reason string) error {
+	s.mu.Lock()
 	node, exists := s.nodeStates[name]
 	if !exists {
+		s.mu.Unlock()
 		return ErrNodeNotFound
 	}
-	node.Status = status
-	return s.save()
+	node.Status = NodeStatusDisabled
+	node.StatusReason = reason
+	node.Available = false
+	s.nodeIdx.put(name, node)
+	err := s.putNode(name, node)
+	s.mu.Unlock()
+
+	s.publish(Event{Type: EventNodeUpdated, Node: node})
+	return err
 }
 
-// ListNodesByLatency returns nodes filtered by latency level
-func (s *Store) ListNodesByLatency(level LatencyLevel) []*EnhancedNode {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RemoveNodeState deletes a node's state, e.g. because it disappeared from
+// both the config and boxmgr's live snapshot.
+func (s *Store) RemoveNodeState(name string) {
+	s.mu.Lock()
+	node, existed := s.nodeStates[name]
+	delete(s.nodeStates, name)
+	if existed {
+		s.nodeIdx.remove(name)
+		_ = s.backend.Delete(CollectionNodeStates, name)
+	}
+	s.mu.Unlock()
 
-	var result []*EnhancedNode
-	for _, node := range s.nodeStates {
-		if node.LatencyLevel == level && node.Status == NodeStatusEnabled && node.Available {
-			result = append(result, node)
-		}
+	if existed {
+		s.publish(Event{Type: EventNodeRemoved, Node: node})
 	}
-	return result
 }
 
-// ListNodesByRegion returns nodes filtered by region
-func (s *Store) ListNodesByRegion(region string) []*EnhancedNode {
+// ListNodeNames returns the keys of every known node state.
+func (s *Store) ListNodeNames() []string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var result []*EnhancedNode
-	for _, node := range s.nodeStates {
-		if node.Region == region && node.Status == NodeStatusEnabled && node.Available {
-			result = append(result, node)
-		}
+	result := make([]string, 0, len(s.nodeStates))
+	for name := range s.nodeStates {
+		result = append(result, name)
 	}
 	return result
 }
 
-// ListNodesBySubscription returns nodes filtered by subscription
-func (s *Store) ListNodesBySubscription(subID string) []*EnhancedNode {
+// Query returns every node matching filter, picking whichever secondary
+// index (or the region+latency composite) covers the most of filter's
+// fields rather than scanning nodeStates.
+func (s *Store) Query(filter NodeFilter) []*EnhancedNode {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.nodeIdx.query(filter)
+}
 
-	var result []*EnhancedNode
-	for _, node := range s.nodeStates {
-		if node.SubscriptionID == subID && node.Status == NodeStatusEnabled {
-			result = append(result, node)
-		}
-	}
-	return result
+// ListNodesByLatency returns nodes filtered by latency level
+func (s *Store) ListNodesByLatency(level LatencyLevel) []*EnhancedNode {
+	return s.Query(NodeFilter{LatencyLevel: level, Status: NodeStatusEnabled, AvailableSet: true, Available: true})
+}
+
+// ListNodesByRegion returns nodes filtered by region
+func (s *Store) ListNodesByRegion(region string) []*EnhancedNode {
+	return s.Query(NodeFilter{Region: region, Status: NodeStatusEnabled, AvailableSet: true, Available: true})
+}
+
+// ListNodesBySubscription returns nodes filtered by subscription
+func (s *Store) ListNodesBySubscription(subID string) []*EnhancedNode {
+	return s.Query(NodeFilter{SubscriptionID: subID, Status: NodeStatusEnabled})
 }
 
 // ListAvailableNodes returns all enabled and available nodes
 func (s *Store) ListAvailableNodes() []*EnhancedNode {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	var result []*EnhancedNode
-	for _, node := range s.nodeStates {
-		if node.Status == NodeStatusEnabled && node.Available {
-			result = append(result, node)
-		}
-	}
-	return result
+	return s.Query(NodeFilter{Status: NodeStatusEnabled, AvailableSet: true, Available: true})
 }
 
 // GetGroupedByLatency returns nodes grouped by latency level
 func (s *Store) GetGroupedByLatency() map[LatencyLevel][]*EnhancedNode {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	result := make(map[LatencyLevel][]*EnhancedNode)
-	for _, node := range s.nodeStates {
-		if node.Status == NodeStatusEnabled && node.Available {
-			result[node.LatencyLevel] = append(result[node.LatencyLevel], node)
-		}
+	for _, node := range s.ListAvailableNodes() {
+		result[node.LatencyLevel] = append(result[node.LatencyLevel], node)
 	}
 	return result
 }
 
 // GetGroupedByRegion returns nodes grouped by region
 func (s *Store) GetGroupedByRegion() map[string][]*EnhancedNode {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	result := make(map[string][]*EnhancedNode)
-	for _, node := range s.nodeStates {
-		if node.Status == NodeStatusEnabled && node.Available {
-			region := node.Region
-			if region == "" {
-				region = "unknown"
-			}
-			result[region] = append(result[region], node)
+	for _, node := range s.ListAvailableNodes() {
+		region := node.Region
+		if region == "" {
+			region = "unknown"
 		}
+		result[region] = append(result[region], node)
 	}
 	return result
 }
 
 // --- Persistence ---
 
-type storeData struct {
-	Subscriptions map[string]*Subscription `json:"subscriptions"`
-	NodeStates    map[string]*EnhancedNode `json:"node_states"`
-	LatencyConfig LatencyConfig            `json:"latency_config"`
+// putSubscription persists a single subscription under its own backend key
+// instead of rewriting the whole collection, so AddSubscription/
+// UpdateSubscription stay O(1) regardless of how many subscriptions exist.
+func (s *Store) putSubscription(sub *Subscription) error {
+	value, err := sub.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(CollectionSubscriptions, sub.ID, value)
+}
+
+// putNode persists a single node state under its own backend key.
+func (s *Store) putNode(key string, node *EnhancedNode) error {
+	value, err := node.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(CollectionNodeStates, key, value)
 }
 
-func (s *Store) save() error {
-	if s.dataDir == "" {
-		return nil
+func (s *Store) putWebhook(wh *Webhook) error {
+	value, err := json.Marshal(wh)
+	if err != nil {
+		return err
 	}
+	return s.backend.Put(CollectionWebhooks, wh.ID, value)
+}
 
-	data := storeData{
-		Subscriptions: s.subscriptions,
-		NodeStates:    s.nodeStates,
-		LatencyConfig: s.latencyConfig,
+// putDeliveryQueue persists the whole retry queue as one record, since it's
+// a small bounded slice (maxQueuedDeliveries) rather than a per-ID
+// collection callers look up individually.
+func (s *Store) putDeliveryQueue() error {
+	value, err := json.Marshal(s.deliveryQueue)
+	if err != nil {
+		return err
 	}
+	return s.backend.Put(CollectionMeta, "delivery_queue", value)
+}
 
-	bytes, err := json.MarshalIndent(data, "", "  ")
+func (s *Store) putLatencyConfig() error {
+	value, err := json.Marshal(s.latencyConfig)
 	if err != nil {
 		return err
 	}
+	return s.backend.Put(CollectionMeta, metaLatencyConfigKey, value)
+}
 
-	return os.WriteFile(filepath.Join(s.dataDir, "store.json"), bytes, 0644)
+func (s *Store) putNextEventID() error {
+	value, err := json.Marshal(s.nextEventID)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(CollectionMeta, metaNextEventIDKey, value)
 }
 
+// load reconstructs in-memory state from the backend's collections. It's
+// called once, from NewStoreWithBackend.
 func (s *Store) load() error {
-	path := filepath.Join(s.dataDir, "store.json")
-	bytes, err := os.ReadFile(path)
+	subs, err := s.backend.List(CollectionSubscriptions)
+	if err != nil {
+		return err
+	}
+	for id, raw := range subs {
+		var sub Subscription
+		if err := sub.UnmarshalBinary(raw); err != nil {
+			continue
+		}
+		s.subscriptions[id] = &sub
+	}
+
+	nodes, err := s.backend.List(CollectionNodeStates)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+		return err
+	}
+	for key, raw := range nodes {
+		var node EnhancedNode
+		if err := node.UnmarshalBinary(raw); err != nil {
+			continue
 		}
+		s.nodeStates[key] = &node
+		s.nodeIdx.put(key, &node)
+	}
+
+	webhooks, err := s.backend.List(CollectionWebhooks)
+	if err != nil {
 		return err
 	}
+	for id, raw := range webhooks {
+		var wh Webhook
+		if err := json.Unmarshal(raw, &wh); err != nil {
+			continue
+		}
+		s.webhooks[id] = &wh
+	}
 
-	var data storeData
-	if err := json.Unmarshal(bytes, &data); err != nil {
+	peers, err := s.backend.List(CollectionPeers)
+	if err != nil {
 		return err
 	}
+	for id, raw := range peers {
+		var peer Peer
+		if err := json.Unmarshal(raw, &peer); err != nil {
+			continue
+		}
+		s.peers[id] = &peer
+	}
 
-	if data.Subscriptions != nil {
-		s.subscriptions = data.Subscriptions
+	if raw, ok, err := s.backend.Get(CollectionMeta, "delivery_queue"); err != nil {
+		return err
+	} else if ok {
+		_ = json.Unmarshal(raw, &s.deliveryQueue)
 	}
-	if data.NodeStates != nil {
-		s.nodeStates = data.NodeStates
+
+	if raw, ok, err := s.backend.Get(CollectionMeta, metaLatencyConfigKey); err != nil {
+		return err
+	} else if ok {
+		_ = json.Unmarshal(raw, &s.latencyConfig)
 	}
-	if data.LatencyConfig.LowThreshold > 0 {
-		s.latencyConfig = data.LatencyConfig
+
+	if raw, ok, err := s.backend.Get(CollectionMeta, metaNextEventIDKey); err != nil {
+		return err
+	} else if ok {
+		_ = json.Unmarshal(raw, &s.nextEventID)
 	}
 
 	return nil
 }
 
-// Save persists all data to disk
+// Save rewrites every collection from the current in-memory state. The
+// targeted mutation methods (AddSubscription, SetNodeStatus, ...) already
+// persist incrementally as they go; Save is for call sites that mutate a
+// *Subscription/*EnhancedNode obtained from Get in place and want it
+// flushed without going through a setter.
 func (s *Store) Save() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.save()
+
+	for _, sub := range s.subscriptions {
+		if err := s.putSubscription(sub); err != nil {
+			return err
+		}
+	}
+	for key, node := range s.nodeStates {
+		if err := s.putNode(key, node); err != nil {
+			return err
+		}
+	}
+	for _, wh := range s.webhooks {
+		if err := s.putWebhook(wh); err != nil {
+			return err
+		}
+	}
+	for _, peer := range s.peers {
+		if err := s.putPeer(peer); err != nil {
+			return err
+		}
+	}
+	if err := s.putDeliveryQueue(); err != nil {
+		return err
+	}
+	if err := s.putLatencyConfig(); err != nil {
+		return err
+	}
+	return s.putNextEventID()
 }
 
 // --- Errors ---
@@ -399,6 +1058,11 @@ func (s *Store) Save() error {
 var (
 	ErrSubscriptionNotFound = &StoreError{Message: "subscription not found"}
 	ErrNodeNotFound         = &StoreError{Message: "node not found"}
+	ErrWebhookNotFound      = &StoreError{Message: "webhook not found"}
+	ErrPeerNotFound         = &StoreError{Message: "peer not found"}
+	// ErrConflict is returned by a GuaranteedUpdateNode tryUpdate func that
+	// wants to fail fast on a concurrent write instead of being retried.
+	ErrConflict = &StoreError{Message: "version conflict"}
 )
 
 type StoreError struct {
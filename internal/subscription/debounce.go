@@ -0,0 +1,33 @@
+package subscription
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer collapses a burst of trigger() calls arriving within delay of
+// each other into a single fn call, the same debounce handleSubscriptions
+// already applies around TriggerReload.
+type debouncer struct {
+	delay time.Duration
+	fn    func()
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newDebouncer(delay time.Duration, fn func()) *debouncer {
+	return &debouncer{delay: delay, fn: fn}
+}
+
+// trigger (re)starts the debounce window; fn runs once delay passes
+// without another trigger() call.
+func (d *debouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.delay, d.fn)
+}
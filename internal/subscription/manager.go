@@ -0,0 +1,76 @@
+// Package subscription manages proxy node subscription sources: the
+// static URL list from the config file plus, for multi-instance
+// deployments, sources discovered from a live etcd or Consul KV prefix.
+package subscription
+
+import (
+	"context"
+	"time"
+
+	"easy_proxies/internal/config"
+	"easy_proxies/internal/store"
+)
+
+// NodeReloader triggers boxmgr's node reload after a subscription changes.
+// It's the same method handleSubscriptions already calls TriggerReload
+// through.
+type NodeReloader interface {
+	TriggerReload(ctx context.Context) error
+}
+
+// reloadDebounce matches the delay handleSubscriptions already uses
+// before triggering a reload, so a burst of discovery events (several
+// keys appearing or disappearing together) collapses into one reload.
+const reloadDebounce = 500 * time.Millisecond
+
+// reloadTimeout bounds the TriggerReload call a debounced discovery event
+// makes.
+const reloadTimeout = 30 * time.Second
+
+// Manager owns the configured SubscriptionSources and debounces the
+// TriggerReload calls discovery sources request.
+type Manager struct {
+	sources  []SubscriptionSource
+	debounce *debouncer
+	cancel   context.CancelFunc
+}
+
+// New builds a Manager with a StaticSource for cfg.Subscriptions plus an
+// Etcd/Consul SubscriptionSource for each configured discovery backend.
+func New(cfg *config.Config, st *store.Store, nodeMgr NodeReloader) *Manager {
+	m := &Manager{debounce: newDebouncer(reloadDebounce, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+		defer cancel()
+		_ = nodeMgr.TriggerReload(ctx)
+	})}
+
+	m.sources = append(m.sources, NewStaticSource(cfg.Subscriptions, st))
+	for _, d := range cfg.SubscriptionDiscovery.Etcd {
+		m.sources = append(m.sources, NewEtcdSource(kvBackend{Endpoint: d.Endpoint, Prefix: d.Prefix}, st, m.debounce.trigger))
+	}
+	for _, d := range cfg.SubscriptionDiscovery.Consul {
+		m.sources = append(m.sources, NewConsulSource(kvBackend{Endpoint: d.Endpoint, Prefix: d.Prefix}, st, m.debounce.trigger))
+	}
+
+	return m
+}
+
+// Start begins every configured SubscriptionSource: Static applies once,
+// Etcd/Consul spawn a background polling goroutine.
+func (m *Manager) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	for _, src := range m.sources {
+		_ = src.Start(ctx)
+	}
+}
+
+// Stop ends every source's background goroutine.
+func (m *Manager) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	for _, src := range m.sources {
+		src.Stop()
+	}
+}
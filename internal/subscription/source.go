@@ -0,0 +1,266 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"easy_proxies/internal/store"
+)
+
+// discoveryPollInterval bounds how often the Etcd/Consul sources re-list
+// their watched prefix. Neither backend's client library is vendored in
+// this tree, so discovery polls each backend's plain KV HTTP API instead
+// of holding open a long-lived watch stream or session lease.
+const discoveryPollInterval = 10 * time.Second
+
+// SubscriptionSource watches one origin of subscription definitions
+// (static config, or a live etcd/Consul KV prefix) and reconciles what it
+// finds into the store, mirroring the static/discovered split Nextcloud
+// spreed-signaling's mcu_proxy uses for its proxy URLs.
+type SubscriptionSource interface {
+	// Start begins watching. Static applies once and returns immediately;
+	// Etcd/Consul spawn a background polling goroutine tied to ctx.
+	Start(ctx context.Context) error
+	// Stop ends any background goroutine started by Start.
+	Stop()
+}
+
+// StaticSource applies a fixed list of subscription URLs once at startup:
+// the original, config-file-only subscription list.
+type StaticSource struct {
+	urls []string
+	st   *store.Store
+}
+
+// NewStaticSource creates a StaticSource for the given URLs.
+func NewStaticSource(urls []string, st *store.Store) *StaticSource {
+	return &StaticSource{urls: urls, st: st}
+}
+
+// Start adds any URL not already present as a subscription. It's
+// idempotent across restarts since it matches on URL before adding.
+func (s *StaticSource) Start(ctx context.Context) error {
+	existing := make(map[string]bool, len(s.urls))
+	for _, sub := range s.st.ListSubscriptions() {
+		existing[sub.URL] = true
+	}
+
+	for _, u := range s.urls {
+		if u == "" || existing[u] {
+			continue
+		}
+		_ = s.st.AddSubscription(&store.Subscription{URL: u, Enabled: true, Origin: store.SubscriptionOriginStatic})
+	}
+	return nil
+}
+
+// Stop is a no-op: StaticSource has no background goroutine.
+func (s *StaticSource) Stop() {}
+
+// kvBackend is the shared (endpoint, prefix) a KV discovery source watches.
+type kvBackend struct {
+	Endpoint string
+	Prefix   string
+}
+
+// kvLister fetches the current key->URL snapshot under a backend's prefix.
+type kvLister func(ctx context.Context) (map[string]string, error)
+
+// pollSource runs a kvLister on discoveryPollInterval, reconciling each
+// snapshot into the store under origin, until ctx is cancelled.
+type pollSource struct {
+	list     kvLister
+	st       *store.Store
+	origin   store.SubscriptionOrigin
+	onChange func()
+	seen     map[string]string // KV key -> store.Subscription.ID
+	cancel   context.CancelFunc
+}
+
+func (p *pollSource) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.seen = make(map[string]string)
+	go p.run(ctx)
+	return nil
+}
+
+func (p *pollSource) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+func (p *pollSource) run(ctx context.Context) {
+	p.poll(ctx)
+	ticker := time.NewTicker(discoveryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.poll(ctx)
+		}
+	}
+}
+
+func (p *pollSource) poll(ctx context.Context) {
+	latest, err := p.list(ctx)
+	if err != nil {
+		return
+	}
+
+	changed := false
+	for key, url := range latest {
+		if _, ok := p.seen[key]; ok {
+			continue
+		}
+		sub := &store.Subscription{URL: url, Enabled: true, Origin: p.origin}
+		if err := p.st.AddSubscription(sub); err != nil {
+			continue
+		}
+		p.seen[key] = sub.ID
+		changed = true
+	}
+	for key, id := range p.seen {
+		if _, ok := latest[key]; ok {
+			continue
+		}
+		_ = p.st.DeleteSubscription(id)
+		delete(p.seen, key)
+		changed = true
+	}
+
+	if changed && p.onChange != nil {
+		p.onChange()
+	}
+}
+
+// NewEtcdSource discovers subscription URLs from an etcd v3 KV prefix via
+// etcd's HTTP/JSON gRPC-gateway (no etcd client library is vendored in
+// this tree). onChange is called after any add/remove, e.g. to debounce a
+// nodeMgr.TriggerReload.
+func NewEtcdSource(backend kvBackend, st *store.Store, onChange func()) SubscriptionSource {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return &pollSource{
+		st:       st,
+		origin:   store.SubscriptionOriginEtcd,
+		onChange: onChange,
+		list:     func(ctx context.Context) (map[string]string, error) { return etcdRange(ctx, client, backend) },
+	}
+}
+
+func etcdRange(ctx context.Context, client *http.Client, backend kvBackend) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(backend.Prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixRangeEnd(backend.Prefix)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(backend.Endpoint, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(decoded.Kvs))
+	for _, kv := range decoded.Kvs {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			continue
+		}
+		result[string(key)] = string(value)
+	}
+	return result, nil
+}
+
+// prefixRangeEnd computes etcd's conventional open range-end for a prefix
+// scan: the prefix with its last byte incremented.
+func prefixRangeEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// NewConsulSource discovers subscription URLs from a Consul KV prefix via
+// Consul's HTTP KV API (no Consul client library is vendored in this
+// tree). onChange is called after any add/remove.
+func NewConsulSource(backend kvBackend, st *store.Store, onChange func()) SubscriptionSource {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return &pollSource{
+		st:       st,
+		origin:   store.SubscriptionOriginConsul,
+		onChange: onChange,
+		list:     func(ctx context.Context) (map[string]string, error) { return consulList(ctx, client, backend) },
+	}
+}
+
+func consulList(ctx context.Context, client *http.Client, backend kvBackend) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/kv/%s?recurse=true", strings.TrimRight(backend.Endpoint, "/"), strings.TrimLeft(backend.Prefix, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+
+	var entries []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(entries))
+	for _, e := range entries {
+		value, err := base64.StdEncoding.DecodeString(e.Value)
+		if err != nil {
+			continue
+		}
+		result[e.Key] = string(value)
+	}
+	return result, nil
+}
@@ -0,0 +1,236 @@
+// Package supervise provides a small suture-style supervisor tree: services
+// register with a Supervisor and are restarted with exponential backoff when
+// they fail, with a circuit breaker that suspends a service that fails too
+// often in too short a window instead of restart-looping it forever.
+package supervise
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Service is a long-running subsystem the Supervisor can start and stop.
+// Serve should block until ctx is done or an unrecoverable error occurs; a
+// returned error (including a recovered panic, converted to an error by the
+// Supervisor) triggers a restart.
+type Service interface {
+	Serve(ctx context.Context) error
+	Stop()
+}
+
+// State describes a supervised service's current lifecycle state.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateBackoff   State = "backoff"
+	StateSuspended State = "suspended"
+	StateStopped   State = "stopped"
+)
+
+// Health is the status of one supervised service, safe to marshal to JSON
+// for the monitor server.
+type Health struct {
+	Name        string    `json:"name"`
+	State       State     `json:"state"`
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastStartAt time.Time `json:"last_start_at,omitempty"`
+	SuspendedAt time.Time `json:"suspended_at,omitempty"`
+}
+
+// Options configures the restart backoff and circuit breaker for a service.
+type Options struct {
+	MinBackoff       time.Duration // initial restart delay, default 1s
+	MaxBackoff       time.Duration // cap on restart delay, default 30s
+	FailureThreshold int           // failures within FailureWindow to trip the breaker, default 2
+	FailureWindow    time.Duration // default 10s ("fail twice rapidly")
+	CooldownPeriod   time.Duration // suspension duration once tripped, default 10m
+}
+
+func (o *Options) setDefaults() {
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 2
+	}
+	if o.FailureWindow <= 0 {
+		o.FailureWindow = 10 * time.Second
+	}
+	if o.CooldownPeriod <= 0 {
+		o.CooldownPeriod = 10 * time.Minute
+	}
+}
+
+// Supervisor restarts a set of registered Services with backoff, and
+// circuit-breaks a service that fails repeatedly in a short window.
+type Supervisor struct {
+	mu       sync.RWMutex
+	children map[string]*child
+}
+
+type child struct {
+	svc       Service
+	opts      Options
+	health    Health
+	failTimes []time.Time
+}
+
+// New creates an empty Supervisor.
+func New() *Supervisor {
+	return &Supervisor{children: make(map[string]*child)}
+}
+
+// Add registers svc under name with the given options and starts
+// supervising it in a background goroutine. ctx controls the lifetime of
+// the whole supervised tree; cancelling it stops every child.
+func (s *Supervisor) Add(ctx context.Context, name string, svc Service, opts Options) {
+	opts.setDefaults()
+	c := &child{svc: svc, opts: opts, health: Health{Name: name, State: StateRunning}}
+
+	s.mu.Lock()
+	s.children[name] = c
+	s.mu.Unlock()
+
+	go s.run(ctx, name, c)
+}
+
+func (s *Supervisor) run(ctx context.Context, name string, c *child) {
+	backoff := c.opts.MinBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			s.setState(name, StateStopped)
+			return
+		default:
+		}
+
+		s.setStart(name)
+		err := serveRecovering(ctx, c.svc)
+		if ctx.Err() != nil {
+			s.setState(name, StateStopped)
+			return
+		}
+
+		s.recordFailure(name, c, err)
+
+		if s.tripped(c) {
+			s.setSuspended(name)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.opts.CooldownPeriod):
+			}
+			s.resetFailures(name, c)
+			backoff = c.opts.MinBackoff
+			continue
+		}
+
+		s.setState(name, StateBackoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > c.opts.MaxBackoff {
+			backoff = c.opts.MaxBackoff
+		}
+	}
+}
+
+// serveRecovering runs svc.Serve, converting a panic into an error so a
+// panicking goroutine no longer silently kills the subsystem forever.
+func serveRecovering(ctx context.Context, svc Service) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return svc.Serve(ctx)
+}
+
+func (s *Supervisor) setStart(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.children[name]; ok {
+		c.health.State = StateRunning
+		c.health.LastStartAt = time.Now()
+	}
+}
+
+func (s *Supervisor) setState(name string, state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.children[name]; ok {
+		c.health.State = state
+	}
+}
+
+func (s *Supervisor) setSuspended(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.children[name]; ok {
+		c.health.State = StateSuspended
+		c.health.SuspendedAt = time.Now()
+	}
+}
+
+func (s *Supervisor) recordFailure(name string, c *child, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.health.Restarts++
+	if err != nil {
+		c.health.LastError = err.Error()
+	}
+	c.failTimes = append(c.failTimes, time.Now())
+}
+
+// tripped reports whether c has failed FailureThreshold times within
+// FailureWindow, pruning older failures as it goes.
+func (s *Supervisor) tripped(c *child) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-c.opts.FailureWindow)
+	kept := c.failTimes[:0]
+	for _, t := range c.failTimes {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	c.failTimes = kept
+	return len(c.failTimes) >= c.opts.FailureThreshold
+}
+
+func (s *Supervisor) resetFailures(name string, c *child) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.failTimes = nil
+}
+
+// Stop calls Stop on every registered service.
+func (s *Supervisor) Stop() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.children {
+		c.svc.Stop()
+	}
+}
+
+// Health returns a snapshot of every supervised service's status, suitable
+// for exposing through the monitor HTTP server.
+func (s *Supervisor) Health() []Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]Health, 0, len(s.children))
+	for _, c := range s.children {
+		result = append(result, c.health)
+	}
+	return result
+}
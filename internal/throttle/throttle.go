@@ -0,0 +1,257 @@
+// Package throttle provides bandwidth rate limiting for proxy connections.
+//
+// It implements a token-bucket limiter ("SlowListener" style) that wraps a
+// net.Listener so every accepted net.Conn has its Read/Write calls gated by
+// bytes/sec buckets. A readrate or writerate of 0 means unlimited.
+package throttle
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket limiting throughput to ratePerSec bytes/sec with
+// burst capacity. A rate of 0 means unlimited and WaitN becomes a no-op.
+type Bucket struct {
+	mu         sync.Mutex
+	rate       int64 // bytes/sec, 0 = unlimited
+	burst      int64
+	tokens     float64
+	lastRefill time.Time
+}
+
+// NewBucket creates a token bucket with the given rate (bytes/sec) and burst
+// capacity (bytes). If burst is 0, it defaults to rate (one second worth).
+func NewBucket(rate, burst int64) *Bucket {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &Bucket{
+		rate:       rate,
+		burst:      burst,
+		tokens:     float64(burst),
+		lastRefill: time.Now(),
+	}
+}
+
+// SetRate updates the bucket's rate and burst at runtime.
+func (b *Bucket) SetRate(rate, burst int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if burst <= 0 {
+		burst = rate
+	}
+	b.rate = rate
+	b.burst = burst
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+}
+
+// Rate returns the current rate and burst (bytes/sec, bytes).
+func (b *Bucket) Rate() (rate, burst int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate, b.burst
+}
+
+// WaitN blocks until n bytes worth of tokens are available, then consumes
+// them. It returns immediately when the bucket is unlimited (rate <= 0).
+// n may exceed burst (slowConn passes whole Read/Write buffers, which can
+// be larger than a tightly-capped node's burst); each iteration only ever
+// waits for up to burst tokens; refillLocked caps tokens at burst, so
+// waiting for more than that would never be satisfied.
+func (b *Bucket) WaitN(n int) {
+	for n > 0 {
+		b.mu.Lock()
+		if b.rate <= 0 {
+			b.mu.Unlock()
+			return
+		}
+		b.refillLocked()
+		take := int64(n)
+		if take > b.burst {
+			take = b.burst
+		}
+		if b.tokens >= float64(take) {
+			b.tokens -= float64(take)
+			n -= int(take)
+			b.mu.Unlock()
+			continue
+		}
+		need := float64(take) - b.tokens
+		wait := time.Duration(need / float64(b.rate) * float64(time.Second))
+		b.mu.Unlock()
+		if wait <= 0 {
+			wait = time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (b *Bucket) refillLocked() {
+	now := time.Now()
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.lastRefill = now
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += elapsed * float64(b.rate)
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
+
+// Rates holds a read/write rate pair, in bytes/sec. Zero means unlimited.
+type Rates struct {
+	ReadRate  int64 `json:"read_rate"`
+	WriteRate int64 `json:"write_rate"`
+	Burst     int64 `json:"burst,omitempty"`
+}
+
+// Limiter owns a global bucket pair plus per-node bucket pairs keyed by
+// outbound node name. Global and per-node buckets are both consulted on
+// every Read/Write, so a node is capped by whichever is tighter.
+type Limiter struct {
+	mu          sync.RWMutex
+	globalRead  *Bucket
+	globalWrite *Bucket
+	nodeRead    map[string]*Bucket
+	nodeWrite   map[string]*Bucket
+}
+
+// Config configures the global buckets at construction time.
+type Config struct {
+	GlobalReadRate  int64
+	GlobalWriteRate int64
+	Burst           int64
+}
+
+// NewLimiter creates a Limiter with global buckets seeded from cfg.
+func NewLimiter(cfg Config) *Limiter {
+	return &Limiter{
+		globalRead:  NewBucket(cfg.GlobalReadRate, cfg.Burst),
+		globalWrite: NewBucket(cfg.GlobalWriteRate, cfg.Burst),
+		nodeRead:    make(map[string]*Bucket),
+		nodeWrite:   make(map[string]*Bucket),
+	}
+}
+
+// SetGlobalRate updates the global read/write rates at runtime.
+func (l *Limiter) SetGlobalRate(r Rates) {
+	l.globalRead.SetRate(r.ReadRate, r.Burst)
+	l.globalWrite.SetRate(r.WriteRate, r.Burst)
+}
+
+// GlobalRate returns the current global read/write rates.
+func (l *Limiter) GlobalRate() Rates {
+	read, burst := l.globalRead.Rate()
+	write, _ := l.globalWrite.Rate()
+	return Rates{ReadRate: read, WriteRate: write, Burst: burst}
+}
+
+// SetNodeRate sets (or clears, if both rates are 0) the per-node rate for
+// the outbound node named name.
+func (l *Limiter) SetNodeRate(name string, r Rates) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rb, ok := l.nodeRead[name]; ok {
+		rb.SetRate(r.ReadRate, r.Burst)
+	} else {
+		l.nodeRead[name] = NewBucket(r.ReadRate, r.Burst)
+	}
+	if wb, ok := l.nodeWrite[name]; ok {
+		wb.SetRate(r.WriteRate, r.Burst)
+	} else {
+		l.nodeWrite[name] = NewBucket(r.WriteRate, r.Burst)
+	}
+}
+
+// NodeRates returns the configured per-node rates, keyed by node name.
+func (l *Limiter) NodeRates() map[string]Rates {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	result := make(map[string]Rates, len(l.nodeRead))
+	for name, rb := range l.nodeRead {
+		read, burst := rb.Rate()
+		write := int64(0)
+		if wb, ok := l.nodeWrite[name]; ok {
+			write, _ = wb.Rate()
+		}
+		result[name] = Rates{ReadRate: read, WriteRate: write, Burst: burst}
+	}
+	return result
+}
+
+func (l *Limiter) nodeBuckets(name string) (read, write *Bucket) {
+	l.mu.RLock()
+	read, ok1 := l.nodeRead[name]
+	write, ok2 := l.nodeWrite[name]
+	l.mu.RUnlock()
+	if ok1 && ok2 {
+		return read, write
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.nodeRead[name]; !ok {
+		l.nodeRead[name] = NewBucket(0, 0)
+	}
+	if _, ok := l.nodeWrite[name]; !ok {
+		l.nodeWrite[name] = NewBucket(0, 0)
+	}
+	return l.nodeRead[name], l.nodeWrite[name]
+}
+
+// WrapListener returns a net.Listener whose accepted connections are gated
+// by the global buckets and the per-node buckets for nodeName.
+func (l *Limiter) WrapListener(ln net.Listener, nodeName string) net.Listener {
+	return &slowListener{Listener: ln, limiter: l, nodeName: nodeName}
+}
+
+type slowListener struct {
+	net.Listener
+	limiter  *Limiter
+	nodeName string
+}
+
+func (sl *slowListener) Accept() (net.Conn, error) {
+	conn, err := sl.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	nodeRead, nodeWrite := sl.limiter.nodeBuckets(sl.nodeName)
+	return &slowConn{
+		Conn:        conn,
+		globalRead:  sl.limiter.globalRead,
+		globalWrite: sl.limiter.globalWrite,
+		nodeRead:    nodeRead,
+		nodeWrite:   nodeWrite,
+	}, nil
+}
+
+// slowConn wraps a net.Conn, gating Read/Write through both a global and a
+// per-node bucket.
+type slowConn struct {
+	net.Conn
+	globalRead  *Bucket
+	globalWrite *Bucket
+	nodeRead    *Bucket
+	nodeWrite   *Bucket
+}
+
+func (c *slowConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.nodeRead.WaitN(n)
+		c.globalRead.WaitN(n)
+	}
+	return n, err
+}
+
+func (c *slowConn) Write(b []byte) (int, error) {
+	c.nodeWrite.WaitN(len(b))
+	c.globalWrite.WaitN(len(b))
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,217 @@
+// Package webhook delivers lifecycle events (node up/down/blacklist,
+// subscription add/delete, reload finished) to externally registered HTTP
+// endpoints, signing each payload and retrying failed deliveries with
+// exponential backoff against a retry queue persisted in the store.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"easy_proxies/internal/store"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature (hex-encoded, over the
+// raw request body) of a delivered payload, computed with the webhook's
+// secret.
+const SignatureHeader = "X-Easy-Proxies-Signature"
+
+const (
+	workerCount = 4
+	maxAttempts = 8
+	minBackoff  = time.Second
+	maxBackoff  = 5 * time.Minute
+	requeuePoll = time.Second
+)
+
+// Payload is the JSON body POSTed to a registered webhook endpoint.
+type Payload struct {
+	EventID   int64     `json:"event_id"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// Dispatcher fans lifecycle events out to every registered store.Webhook
+// whose event mask matches. It implements supervise.Service so app.go can
+// run it under the supervisor tree alongside boxmgr/subscription/speedtest.
+type Dispatcher struct {
+	st     *store.Store
+	client *http.Client
+	work   chan *store.WebhookDelivery
+}
+
+// New creates a Dispatcher backed by st.
+func New(st *store.Store) *Dispatcher {
+	return &Dispatcher{
+		st:     st,
+		client: &http.Client{Timeout: 10 * time.Second},
+		work:   make(chan *store.WebhookDelivery, 256),
+	}
+}
+
+// Serve starts the worker pool and the retry-queue poller, then blocks
+// until ctx is done.
+func (d *Dispatcher) Serve(ctx context.Context) error {
+	for i := 0; i < workerCount; i++ {
+		go d.worker(ctx)
+	}
+
+	d.requeuePending()
+
+	ticker := time.NewTicker(requeuePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.requeuePending()
+		}
+	}
+}
+
+// Stop is a no-op: Serve's goroutines exit on ctx cancellation, and the
+// retry queue itself lives in the store, not in the Dispatcher.
+func (d *Dispatcher) Stop() {}
+
+// Emit queues evt for delivery to every enabled webhook whose event mask
+// includes eventType (an empty mask matches everything); each delivery is
+// signed with its webhook's secret when it is sent. Emit
+// enqueues rather than sending inline, so it never blocks the state
+// transition (node status change, subscription mutation, ...) that calls
+// it.
+func (d *Dispatcher) Emit(eventType string, data any) {
+	id := d.st.NextEventID()
+	body, err := json.Marshal(Payload{EventID: id, Type: eventType, Timestamp: time.Now(), Data: data})
+	if err != nil {
+		return
+	}
+
+	for _, wh := range d.st.ListWebhooks() {
+		if !wh.Enabled || !matchesMask(wh.Events, eventType) {
+			continue
+		}
+
+		delivery := &store.WebhookDelivery{
+			ID:            fmt.Sprintf("%d-%s", id, wh.ID),
+			WebhookID:     wh.ID,
+			EventID:       id,
+			Payload:       body,
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.st.EnqueueDelivery(delivery); err != nil {
+			continue
+		}
+
+		select {
+		case d.work <- delivery:
+		default:
+			// Worker pool is momentarily saturated; the next requeuePending
+			// tick will pick this delivery back up from the persisted queue.
+		}
+	}
+}
+
+func matchesMask(mask []string, eventType string) bool {
+	if len(mask) == 0 {
+		return true
+	}
+	for _, m := range mask {
+		if m == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// requeuePending pushes every due, persisted delivery onto the worker
+// channel. It's how deliveries queued before a restart (or dropped by a
+// saturated worker pool) get picked back up.
+func (d *Dispatcher) requeuePending() {
+	now := time.Now()
+	for _, delivery := range d.st.ListPendingDeliveries() {
+		if delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		select {
+		case d.work <- delivery:
+		default:
+		}
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-d.work:
+			d.attempt(ctx, delivery)
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *store.WebhookDelivery) {
+	wh, err := d.st.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		// Webhook was deleted since this delivery was queued.
+		d.st.RemoveDelivery(delivery.ID)
+		return
+	}
+
+	if err := d.send(ctx, wh, delivery); err != nil {
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= maxAttempts {
+			d.st.RemoveDelivery(delivery.ID)
+			return
+		}
+		delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+		_ = d.st.UpdateDelivery(delivery)
+		return
+	}
+
+	d.st.RemoveDelivery(delivery.ID)
+}
+
+func (d *Dispatcher) send(ctx context.Context, wh *store.Webhook, delivery *store.WebhookDelivery) error {
+	mac := hmac.New(sha256.New, []byte(wh.Secret))
+	mac.Write(delivery.Payload)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// backoff returns an exponential delay for the given attempt count, capped
+// at maxBackoff.
+func backoff(attempts int) time.Duration {
+	d := minBackoff << uint(attempts-1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}